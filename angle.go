@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"calculator/trig"
+)
+
+/**
+This file contains the angle mode of the session. Trigonometry functions take
+their input in the current angle unit and inverse functions return their result
+in it. Independent of the mode, a number can be given in a specific unit with a
+suffix, e.g. sin(30deg), and a result can be converted to another unit with ->,
+e.g. arcsin(0.5) -> deg.
+*/
+
+// AngleUnit is a unit in which angles are measured.
+type AngleUnit string
+
+const (
+	// Degrees divide a full turn into 360.
+	Degrees AngleUnit = "deg"
+	// Radians divide a full turn into 2 pi.
+	Radians AngleUnit = "rad"
+	// Gradians divide a full turn into 400.
+	Gradians AngleUnit = "grad"
+)
+
+// Name returns the unit spelled out, e.g. degrees.
+func (u AngleUnit) Name() string {
+	switch u {
+	case Degrees:
+		return "degrees"
+	case Gradians:
+		return "gradians"
+	default:
+		return "radians"
+	}
+}
+
+// IsAngleUnit reports whether name is deg, rad or grad.
+func IsAngleUnit(name string) bool {
+	switch AngleUnit(name) {
+	case Degrees, Radians, Gradians:
+		return true
+	}
+	return false
+}
+
+// ToRadians converts an angle x in unit to radians.
+func ToRadians(x float64, unit AngleUnit) float64 {
+	switch unit {
+	case Degrees:
+		return trig.ConvertToRadian(x)
+	case Gradians:
+		return (x / 200.0) * math.Pi
+	default:
+		return x
+	}
+}
+
+// FromRadians converts an angle x in radians to unit.
+func FromRadians(x float64, unit AngleUnit) float64 {
+	switch unit {
+	case Degrees:
+		return trig.ConvertFromRadian(x)
+	case Gradians:
+		return (x / math.Pi) * 200.0
+	default:
+		return x
+	}
+}
+
+// ConvertAngle converts an angle x from one unit to another.
+func ConvertAngle(x float64, from, to AngleUnit) float64 {
+	if from == to {
+		return x
+	}
+	return FromRadians(ToRadians(x, from), to)
+}
+
+// SetAngleUnit changes the angle mode of the session.
+func (s *Session) SetAngleUnit(unit AngleUnit) {
+	s.Angle = unit
+	fmt.Printf("Angle mode: %s.\n", unit.Name())
+}
+
+// AngleConversionNode converts the value of Expr from one angle unit to
+// another. An empty unit stands for the angle mode of the session, so 30deg
+// converts from degrees to the mode and x -> deg from the mode to degrees.
+type AngleConversionNode struct {
+	Expr Node
+	From AngleUnit
+	To   AngleUnit
+}
+
+// Eval evaluates the expression and converts the result.
+func (n AngleConversionNode) Eval(scope *Scope) (Value, error) {
+	v, err := n.Expr.Eval(scope)
+	if err != nil {
+		return Value{}, err
+	}
+	if !v.IsNumber() {
+		return Value{}, errors.New("only numbers can be converted between angle units")
+	}
+	from, to := n.From, n.To
+	if from == "" {
+		from = CurrentSession.Angle
+	}
+	if to == "" {
+		to = CurrentSession.Angle
+	}
+	if v.Kind == ComplexKind {
+		return ComplexValue(complexAngle(v.Complex, from, to)), nil
+	}
+	if PrecisionEnabled() && isPreciseNumber(v) {
+		return PreciseValue(preciseAngle(v.AsPrecise(), from, to)), nil
+	}
+	return FloatValue(ConvertAngle(v.AsFloat(), from, to)), nil
+}
+
+// angleInput adapts a trigonometry function so that its first argument is
+// taken in the angle mode of the session.
+func angleInput(f func(args []Value) (Value, error)) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		if !args[0].IsNumber() {
+			return f(args)
+		}
+		converted := append([]Value{FloatValue(ToRadians(args[0].AsFloat(), CurrentSession.Angle))}, args[1:]...)
+		if args[0].Kind == ComplexKind {
+			converted[0] = ComplexValue(complexAngle(args[0].Complex, CurrentSession.Angle, Radians))
+		} else if PrecisionEnabled() && isPreciseNumber(args[0]) {
+			converted[0] = PreciseValue(preciseAngle(args[0].AsPrecise(), CurrentSession.Angle, Radians))
+		}
+		return f(converted)
+	}
+}
+
+// angleOutput adapts an inverse trigonometry function so that its result is
+// returned in the angle mode of the session.
+func angleOutput(f func(args []Value) (Value, error)) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		v, err := f(args)
+		if err != nil {
+			return Value{}, err
+		}
+		if v.Kind == ComplexKind {
+			return ComplexValue(complexAngle(v.Complex, Radians, CurrentSession.Angle)), nil
+		}
+		if PrecisionEnabled() && isPreciseNumber(v) {
+			return PreciseValue(preciseAngle(v.AsPrecise(), Radians, CurrentSession.Angle)), nil
+		}
+		return FloatValue(FromRadians(v.AsFloat(), CurrentSession.Angle)), nil
+	}
+}
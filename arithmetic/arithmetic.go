@@ -1,9 +1,27 @@
-package main
+// Package arithmetic implements integer arithmetic through bit manipulation,
+// long multiplication and division, and approximations of pi, square roots,
+// e^x and logarithms through series. Functions with a restricted domain return
+// one of the errors below instead of panicking.
+package arithmetic
 
 import (
+	"errors"
 	"math"
 )
 
+// Errors returned for inputs outside the domain of a function.
+var (
+	ErrDivisionByZero = errors.New("division by zero")
+	ErrKOutOfRange    = errors.New("k must be between 0 and n")
+	ErrNotWholeNumber = errors.New("factorial is only defined on whole numbers n >= 0")
+	ErrSqrtDomain     = errors.New("domain of sqrt is x >= 0")
+	ErrMarginOfError  = errors.New("margin of error must be positive")
+	ErrLogDomain      = errors.New("domain of logarithms is x > 0")
+)
+
+// maxFloatFactorial is the largest n whose factorial fits in a float64.
+const maxFloatFactorial = 170
+
 // Addition function takes in a variable number of inputs and adds
 // them using bit manipulation.
 func Addition(numbers ...int) int {
@@ -77,20 +95,7 @@ func LongMultiplication(x, y int) int {
 // IntArray[0] = as dummy value, IntArray[1] = unit position (10^1), IntArray[2] = tenth position (10^2)
 // IntArray[3] = hundredth position (10^3) ...
 func LongMultiplicationHelper(x, y []int) int {
-	numOfDigitsInX := len(x) - 1
-	numOfDigitsInY := len(y) - 1
-	product := make([]int, numOfDigitsInX+numOfDigitsInY+1)
-
-	for yi := 1; yi < len(y); yi++ {
-		carry := 0
-		for xi := 1; xi < len(x); xi++ {
-			product[xi+yi-1] += carry + x[xi]*y[yi]
-			carry = product[xi+yi-1] / 10
-			product[xi+yi-1] = product[xi+yi-1] % 10
-		}
-		product[yi+numOfDigitsInX] += carry
-	}
-	return MakeIntFromIntArray(product)
+	return MakeIntFromIntArray(append([]int{0}, multiplyDigits(x[1:], y[1:])...))
 }
 
 // KaratsubaMultiplication is an implementation of multiplication for large
@@ -104,7 +109,7 @@ func KaratsubaMultiplication(x, y int) int {
 	maxNumberOfDigits := MaxBetween(CountNumDigits(x), CountNumDigits(y))
 	m := maxNumberOfDigits / 2
 
-	tenToThePowerM := ToThePowerInt(10, m)
+	tenToThePowerM := toThePowerInt(10, m)
 
 	high1 := x / tenToThePowerM
 	low1 := x % tenToThePowerM
@@ -115,7 +120,7 @@ func KaratsubaMultiplication(x, y int) int {
 	c := KaratsubaMultiplication(low1, low2)
 	d := KaratsubaMultiplication((low1 + high1), (low2 + high2))
 
-	return (a * ToThePowerInt(10, 2*m)) + ((d - a - c) * ToThePowerInt(10, m)) + c
+	return (a * toThePowerInt(10, 2*m)) + ((d - a - c) * toThePowerInt(10, m)) + c
 }
 
 // KaratsubaMultiplicationFast uses CountNumDigitsFast instead of
@@ -127,7 +132,7 @@ func KaratsubaMultiplicationFast(x, y int) int {
 
 	m := MaxBetween(CountNumDigitsFast(x), CountNumDigitsFast(y)) / 2
 
-	tenToThePowerM := ToThePowerInt(10, m)
+	tenToThePowerM := toThePowerInt(10, m)
 
 	a := x / tenToThePowerM
 	b := x % tenToThePowerM
@@ -138,36 +143,45 @@ func KaratsubaMultiplicationFast(x, y int) int {
 	bd := KaratsubaMultiplication(b, d)
 	adplusbc := KaratsubaMultiplication((a+b), (c+d)) - ac - bd
 
-	return (ac * ToThePowerInt(10, 2*m)) + (adplusbc * ToThePowerInt(10, m)) + bd
+	return (ac * toThePowerInt(10, 2*m)) + (adplusbc * toThePowerInt(10, m)) + bd
 }
 
-// SlowDivision is a naive implementation of division through subtraction.
-func SlowDivision(dividend, divisor int) int {
+// SlowDivision is a naive implementation of division through subtraction. It
+// only divides by positive divisors.
+func SlowDivision(dividend, divisor int) (int, error) {
+	if divisor <= 0 {
+		return 0, ErrDivisionByZero
+	}
 	quotient := 0
 	for dividend >= divisor {
 		dividend -= divisor
 		quotient++
 	}
-	return quotient
+	return quotient, nil
 }
 
 // LongDivision implements the common long division technique of dividing 2
 // numbers that is typically done by hand.
-func LongDivision(dividend, divisor int) int {
+func LongDivision(dividend, divisor int) (int, error) {
+	if divisor == 0 {
+		return 0, ErrDivisionByZero
+	}
 	sign := -1
 	if (dividend > 0 && divisor > 0) || (dividend < 0 && divisor < 0) {
 		sign = 1
 	}
 
-	return sign * LongDivisionHelper(uint(Abs(dividend)), uint(Abs(divisor)))
+	return sign * LongDivisionHelper(uint(Abs(dividend)), uint(Abs(divisor))), nil
 }
 
 // LongDivisionHelper is a helper function for LongDivision
 func LongDivisionHelper(dividend, divisor uint) int {
 
+	// current is unsigned so that shifting it up to the top bit and back down
+	// ends at 0, which a negative int never does.
 	denominator := divisor
-	current := 1
-	answer := 0
+	current := uint(1)
+	answer := uint(0)
 
 	if denominator > dividend {
 		return 0
@@ -193,25 +207,25 @@ func LongDivisionHelper(dividend, divisor uint) int {
 		current >>= 1
 		denominator >>= 1
 	}
-	return answer
+	return int(answer)
 }
 
 // Permutation computes and returns nPk.
-func Permutation(n, k int) int {
-	if k > n {
-		panic("k must be less than n")
+func Permutation(n, k int) (int, error) {
+	if k > n || k < 0 {
+		return 0, ErrKOutOfRange
 	}
 	product := 1
 	for i := n; i > n-k; i-- {
 		product = product * i
 	}
-	return product
+	return product, nil
 }
 
 // Combination computes and returns nCk.
-func Combination(n, k int) int {
-	if k > n {
-		panic("k must be less than n")
+func Combination(n, k int) (int, error) {
+	if k > n || k < 0 {
+		return 0, ErrKOutOfRange
 	}
 
 	var limit int
@@ -226,26 +240,33 @@ func Combination(n, k int) int {
 	}
 
 	// This computation is done in float to prevent overflow of integers
-	product := float64(1) / Factorial(float64(factorial))
+	product := float64(1) / floatFactorial(float64(factorial))
 
 	for i := n; i > limit; i-- {
 		product = product * float64(i)
 	}
-	return int(math.Ceil(product))
+	return int(math.Ceil(product)), nil
 }
 
-// Factorial computes and returns n!
-func Factorial(n float64) float64 {
-	var absFactorial float64
-	if n == 1 || n == 0 {
-		return 1
-	} else if n > 1 {
-		return n * Factorial(n-1)
-	} else { // n < 1
-		n = n * -1.0
-		absFactorial = n * Factorial(n-1)
-		return -1.0 * absFactorial
+// Factorial computes and returns n! for a whole number n >= 0.
+func Factorial(n float64) (float64, error) {
+	if n < 0 || n != math.Trunc(n) || math.IsInf(n, 0) {
+		return 0, ErrNotWholeNumber
+	}
+	return floatFactorial(n), nil
+}
+
+// floatFactorial multiplies the factors of n! one by one. Past 170! the
+// product no longer fits a float64, so it returns +Inf without doing any work.
+func floatFactorial(n float64) float64 {
+	if n > maxFloatFactorial {
+		return math.Inf(1)
 	}
+	product := 1.0
+	for i := 2.0; i <= n; i++ {
+		product *= i
+	}
+	return product
 }
 
 // Pi approximates the value of the constant Pi through Gregory Leibniz series
@@ -280,13 +301,21 @@ func AbsFloat(x float64) float64 {
 	return x
 }
 
-// ToThePowerInt computes and returns base^power.
-func ToThePowerInt(base, power int) int {
+// ToThePowerInt computes and returns base^power. Negative powers are
+// truncated towards zero like integer division.
+func ToThePowerInt(base, power int) (int, error) {
+	if base == 0 && power < 0 {
+		return 0, ErrDivisionByZero
+	}
+	return toThePowerInt(base, power), nil
+}
+
+func toThePowerInt(base, power int) int {
 	if power == 0 {
 		return 1
 	}
 
-	temp := ToThePowerInt(base, int(power/2))
+	temp := toThePowerInt(base, int(power/2))
 
 	if power%2 == 0 {
 		return temp * temp
@@ -309,7 +338,10 @@ func ToThePowerFloat(base, power float64) float64 {
 
 // NewtonianSquareRoot computes the square root of a number, x through binary
 // search within the margin of error n.
-func NewtonianSquareRoot(x float64, n float64) float64 {
+func NewtonianSquareRoot(x float64, n float64) (float64, error) {
+	if err := checkSquareRoot(x, n); err != nil {
+		return 0, err
+	}
 	m := x / 2
 	for AbsFloat(m*m-x) > n {
 		if m*m > x {
@@ -318,43 +350,94 @@ func NewtonianSquareRoot(x float64, n float64) float64 {
 			m += m / 2
 		}
 	}
-	return m
+	return m, nil
 }
 
 // HeronsSquareRoot computes the square root of a number through convergence
 // using the Heron or Babsylonian method. It computes square root of x within the
-// margin of error, n.
-func HeronsSquareRoot(x float64, n float64) float64 {
+// margin of error, n. After the first step the guesses only decrease, so it
+// also stops once a guess no longer improves, when n is below what a float can
+// resolve around x.
+func HeronsSquareRoot(x float64, n float64) (float64, error) {
+	if err := checkSquareRoot(x, n); err != nil {
+		return 0, err
+	}
 	guess := x / 2.0
-	for AbsFloat(guess*guess-x) > n {
-		guess = 0.5 * (guess + x/guess)
+	for first := true; AbsFloat(guess*guess-x) > n; first = false {
+		next := 0.5 * (guess + x/guess)
+		if !first && next >= guess {
+			break
+		}
+		guess = next
+	}
+	return guess, nil
+}
+
+// checkSquareRoot rejects the inputs for which the square root functions would
+// never converge.
+func checkSquareRoot(x, margin float64) error {
+	if x < 0 {
+		return ErrSqrtDomain
+	}
+	if !(margin > 0) {
+		return ErrMarginOfError
 	}
-	return guess
+	return nil
 }
 
 // Exponent computes e^x using Taylor Series expanded to the n-th term.
 func Exponent(x float64, n int) float64 {
 	exponentValue := 1.0 + x
 	for i := 0; i < n; i++ {
-		exponentValue += ToThePowerFloat(x, float64(i+2)) / Factorial(float64(i+2))
+		exponentValue += ToThePowerFloat(x, float64(i+2)) / floatFactorial(float64(i+2))
 	}
 	return exponentValue
 }
 
-// NaturalLog computes ln(x) using a Taylor Series expanded to the a-th term.
-func NaturalLog(x float64, a int) float64 {
-	halfOfNaturalLogValue := (x - 1) / (x + 1)
-	n := 3.0
-	for i := 0; i < a; i++ {
-		halfOfNaturalLogValue += (1 / n) * ToThePowerFloat(halfOfNaturalLogValue, n)
-		n += 2
+// NaturalLog computes ln(x) using the Taylor Series of ln((1+y)/(1-y)),
+// 2(y + y^3/3 + y^5/5 + ...) with y = (x-1)/(x+1), expanded to the a-th term.
+// Like ComplexNaturalLog it first scales x by a power of 2 to between
+// sqrt(2)/2 and sqrt(2) and takes square roots to bring it close to 1, so that
+// the series converges quickly for every x:
+//
+//	ln(x) = e*ln(2) + 2^k ln(sqrt^k(x / 2^e))
+func NaturalLog(x float64, a int) (float64, error) {
+	if !(x > 0) {
+		return 0, ErrLogDomain
+	}
+	mantissa, e := math.Frexp(x)
+	if mantissa < math.Sqrt2/2 {
+		mantissa *= 2
+		e--
+	}
+	for k := 0; k < logReductions; k++ {
+		mantissa, _ = HeronsSquareRoot(mantissa, ExactMargin)
+	}
+	ln := float64(int(1)<<logReductions) * realLogSeries(mantissa, a)
+	if e != 0 {
+		ln += float64(e) * realLogSeries(2, a)
+	}
+	return ln, nil
+}
+
+// realLogSeries expands ln(x) = 2(y + y^3/3 + y^5/5 + ...) with
+// y = (x-1)/(x+1) to n terms after y.
+func realLogSeries(x float64, n int) float64 {
+	y := (x - 1) / (x + 1)
+	ySquared := y * y
+	power := y
+	sum := y
+	for k := 1; k <= n; k++ {
+		power *= ySquared
+		sum += power / float64(2*k+1)
 	}
-	return 2 * halfOfNaturalLogValue
+	return 2 * sum
 }
 
 // LogBaseTen computes the value of log(x) by conversion of ln(x). More on this
 // method can be found here:
 // http://mathonweb.com/help_ebook/html/algorithms.htm#log
-func LogBaseTen(x float64, a int) float64 {
-	return 0.43429448 * NaturalLog(x, 100)
+func LogBaseTen(x float64, a int) (float64, error) {
+	ln, err := NaturalLog(x, 100)
+	return 0.43429448 * ln, err
 }
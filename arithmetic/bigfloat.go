@@ -0,0 +1,114 @@
+package arithmetic
+
+import (
+	"math/big"
+)
+
+/**
+This file contains the series of Exponent, NaturalLog, LogBaseTen and Pi
+evaluated in arbitrary precision. Results have the precision of x, so
+increasing the number of terms n keeps yielding more correct digits instead of
+stopping at the 16 digits of a float64.
+*/
+
+// newFloat returns a zero big.Float with prec bits of mantissa.
+func newFloat(prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec)
+}
+
+// BigExponent computes e^x with the Taylor Series 1 + x + x^2/2! + ... expanded
+// to n terms after x, like Exponent.
+func BigExponent(x *big.Float, n int) *big.Float {
+	prec := x.Prec()
+	term := newFloat(prec).Set(x)
+	sum := newFloat(prec).SetInt64(1)
+	sum.Add(sum, term)
+	for k := 2; k < n+2; k++ {
+		term.Mul(term, x)
+		term.Quo(term, newFloat(prec).SetInt64(int64(k)))
+		sum.Add(sum, term)
+	}
+	return sum
+}
+
+// BigNaturalLog computes ln(x). x is first written as m * 2^e with m between
+// 0.5 and 1, so that ln(x) = ln(m) + e*ln(2), and both logarithms are expanded
+// to n terms of the series ln(m) = 2(y + y^3/3 + y^5/5 + ...) with
+// y = (m-1)/(m+1).
+func BigNaturalLog(x *big.Float, n int) (*big.Float, error) {
+	if x.Sign() <= 0 {
+		return nil, ErrLogDomain
+	}
+	prec := x.Prec()
+	m := newFloat(prec)
+	e := x.MantExp(m)
+	ln := logSeries(m, n)
+	if e != 0 {
+		ln2 := logSeries(newFloat(prec).SetInt64(2), n)
+		ln.Add(ln, ln2.Mul(ln2, newFloat(prec).SetInt64(int64(e))))
+	}
+	return ln, nil
+}
+
+// logSeries expands ln(x) = 2(y + y^3/3 + y^5/5 + ...) with y = (x-1)/(x+1)
+// to n terms after y.
+func logSeries(x *big.Float, n int) *big.Float {
+	prec := x.Prec()
+	one := newFloat(prec).SetInt64(1)
+	y := newFloat(prec).Sub(x, one)
+	y.Quo(y, newFloat(prec).Add(x, one))
+	ySquared := newFloat(prec).Mul(y, y)
+	power := newFloat(prec).Set(y)
+	sum := newFloat(prec).Set(y)
+	for k := 1; k <= n; k++ {
+		power.Mul(power, ySquared)
+		term := newFloat(prec).Quo(power, newFloat(prec).SetInt64(int64(2*k+1)))
+		sum.Add(sum, term)
+	}
+	return sum.Mul(sum, newFloat(prec).SetInt64(2))
+}
+
+// BigLogBaseTen computes log(x) as ln(x) / ln(10).
+func BigLogBaseTen(x *big.Float, n int) (*big.Float, error) {
+	ln, err := BigNaturalLog(x, n)
+	if err != nil {
+		return nil, err
+	}
+	ln10, _ := BigNaturalLog(newFloat(x.Prec()).SetInt64(10), n)
+	return ln.Quo(ln, ln10), nil
+}
+
+// BigPi computes pi with prec bits of precision. The Gregory-Leibniz series of
+// Pi gains a digit only every ten times as many terms, so Machin's formula
+// pi = 16 arctan(1/5) - 4 arctan(1/239) is used instead, with both arctangents
+// expanded to n terms.
+func BigPi(n int, prec uint) *big.Float {
+	a := newFloat(prec).SetInt64(1)
+	a.Quo(a, newFloat(prec).SetInt64(5))
+	b := newFloat(prec).SetInt64(1)
+	b.Quo(b, newFloat(prec).SetInt64(239))
+	pi := ArctanSeries(a, n)
+	pi.Mul(pi, newFloat(prec).SetInt64(16))
+	b = ArctanSeries(b, n)
+	b.Mul(b, newFloat(prec).SetInt64(4))
+	return pi.Sub(pi, b)
+}
+
+// ArctanSeries expands arctan(x) = x - x^3/3 + x^5/5 - ... to n terms after x.
+// It converges for |x| <= 1, quickly for small x.
+func ArctanSeries(x *big.Float, n int) *big.Float {
+	prec := x.Prec()
+	xSquared := newFloat(prec).Mul(x, x)
+	power := newFloat(prec).Set(x)
+	sum := newFloat(prec).Set(x)
+	for k := 1; k <= n; k++ {
+		power.Mul(power, xSquared)
+		term := newFloat(prec).Quo(power, newFloat(prec).SetInt64(int64(2*k+1)))
+		if k%2 == 1 {
+			sum.Sub(sum, term)
+		} else {
+			sum.Add(sum, term)
+		}
+	}
+	return sum
+}
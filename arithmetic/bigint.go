@@ -0,0 +1,349 @@
+package arithmetic
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/**
+This file contains BigInt, an integer of any size held as an array of its
+decimal digits, and the long-hand arithmetic on it: addition, subtraction,
+long and Karatsuba multiplication, long division, powers and factorials.
+*/
+
+// Errors returned by the BigInt functions.
+var (
+	ErrInvalidBigInt = errors.New("not an integer")
+	ErrNegativePower = errors.New("power must not be negative")
+)
+
+// karatsubaCutoff is the number of digits below which Karatsuba multiplication
+// falls back to long multiplication, which is faster for short numbers.
+const karatsubaCutoff = 32
+
+// BigInt is an integer of any size. Its digits are stored least significant
+// first, like the digit arrays of MakeIntArrayFromInt without the dummy value
+// at index 0, e.g. 123 -> {3, 2, 1}. Zero has no digits. The zero value is 0.
+type BigInt struct {
+	negative bool
+	digits   []int
+}
+
+// NewBigInt converts an int to a BigInt.
+func NewBigInt(x int) BigInt {
+	n := BigInt{negative: x < 0}
+	for x != 0 {
+		digit := x % 10
+		if digit < 0 {
+			digit = -digit
+		}
+		n.digits = append(n.digits, digit)
+		x /= 10
+	}
+	return n
+}
+
+// ParseBigInt reads a decimal integer of any length with an optional sign,
+// e.g. -123456789012345678901234567890.
+func ParseBigInt(s string) (BigInt, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	if negative || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	if s == "" {
+		return BigInt{}, ErrInvalidBigInt
+	}
+	digits := make([]int, len(s))
+	for i, r := range s {
+		if r < '0' || r > '9' {
+			return BigInt{}, ErrInvalidBigInt
+		}
+		digits[len(s)-1-i] = int(r - '0')
+	}
+	return normalize(BigInt{negative, digits}), nil
+}
+
+// normalize removes leading zeros and the sign of zero.
+func normalize(x BigInt) BigInt {
+	n := len(x.digits)
+	for n > 0 && x.digits[n-1] == 0 {
+		n--
+	}
+	x.digits = x.digits[:n]
+	if n == 0 {
+		x.negative = false
+	}
+	return x
+}
+
+// String returns the decimal digits of x.
+func (x BigInt) String() string {
+	if len(x.digits) == 0 {
+		return "0"
+	}
+	var b strings.Builder
+	if x.negative {
+		b.WriteByte('-')
+	}
+	for i := len(x.digits) - 1; i >= 0; i-- {
+		b.WriteByte(byte('0' + x.digits[i]))
+	}
+	return b.String()
+}
+
+// Sign returns -1, 0 or 1 for negative, zero and positive x.
+func (x BigInt) Sign() int {
+	switch {
+	case len(x.digits) == 0:
+		return 0
+	case x.negative:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// NumDigits returns the number of decimal digits of x.
+func (x BigInt) NumDigits() int {
+	return MaxBetween(len(x.digits), 1)
+}
+
+// Neg returns -x.
+func (x BigInt) Neg() BigInt {
+	return normalize(BigInt{!x.negative, x.digits})
+}
+
+// Abs returns |x|.
+func (x BigInt) Abs() BigInt {
+	return BigInt{false, x.digits}
+}
+
+// Int returns x as an int if it fits in one.
+func (x BigInt) Int() (int, bool) {
+	if len(x.digits) > 19 {
+		return 0, false
+	}
+	var magnitude uint64
+	for i := len(x.digits) - 1; i >= 0; i-- {
+		magnitude = magnitude*10 + uint64(x.digits[i])
+	}
+	switch {
+	case !x.negative && magnitude <= 1<<63-1:
+		return int(magnitude), true
+	case x.negative && magnitude <= 1<<63:
+		return int(-magnitude), true
+	}
+	return 0, false
+}
+
+// Float returns the float closest to x, or an infinity if x is too large. The
+// digits are parsed as a whole, so that x is rounded only once.
+func (x BigInt) Float() float64 {
+	f, _ := strconv.ParseFloat(x.String(), 64)
+	return f
+}
+
+// Cmp returns -1, 0 or 1 when x is less than, equal to or greater than y.
+func (x BigInt) Cmp(y BigInt) int {
+	switch {
+	case x.negative && !y.negative:
+		return -1
+	case !x.negative && y.negative:
+		return 1
+	case x.negative:
+		return compareDigits(y.digits, x.digits)
+	default:
+		return compareDigits(x.digits, y.digits)
+	}
+}
+
+// compareDigits compares the magnitudes of two normalized digit arrays.
+func compareDigits(x, y []int) int {
+	if len(x) != len(y) {
+		if len(x) < len(y) {
+			return -1
+		}
+		return 1
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		if x[i] != y[i] {
+			if x[i] < y[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// addDigits adds the magnitudes of two digit arrays, carrying like addition by
+// hand.
+func addDigits(x, y []int) []int {
+	sum := make([]int, MaxBetween(len(x), len(y))+1)
+	carry := 0
+	for i := range sum {
+		s := carry
+		if i < len(x) {
+			s += x[i]
+		}
+		if i < len(y) {
+			s += y[i]
+		}
+		sum[i], carry = s%10, s/10
+	}
+	return sum
+}
+
+// subtractDigits subtracts the magnitude y from the larger magnitude x,
+// borrowing like subtraction by hand.
+func subtractDigits(x, y []int) []int {
+	difference := make([]int, len(x))
+	borrow := 0
+	for i := range x {
+		d := x[i] - borrow
+		if i < len(y) {
+			d -= y[i]
+		}
+		borrow = 0
+		if d < 0 {
+			d += 10
+			borrow = 1
+		}
+		difference[i] = d
+	}
+	return difference
+}
+
+// BigAdd returns x + y.
+func BigAdd(x, y BigInt) BigInt {
+	if x.negative == y.negative {
+		return normalize(BigInt{x.negative, addDigits(x.digits, y.digits)})
+	}
+	if compareDigits(x.digits, y.digits) >= 0 {
+		return normalize(BigInt{x.negative, subtractDigits(x.digits, y.digits)})
+	}
+	return normalize(BigInt{y.negative, subtractDigits(y.digits, x.digits)})
+}
+
+// BigSubtract returns x - y.
+func BigSubtract(x, y BigInt) BigInt {
+	return BigAdd(x, y.Neg())
+}
+
+// multiplyDigits multiplies two digit arrays, least significant digit first,
+// with long multiplication.
+func multiplyDigits(x, y []int) []int {
+	product := make([]int, len(x)+len(y)+1)
+	for yi := range y {
+		carry := 0
+		for xi := range x {
+			product[xi+yi] += carry + x[xi]*y[yi]
+			carry = product[xi+yi] / 10
+			product[xi+yi] %= 10
+		}
+		product[yi+len(x)] += carry
+	}
+	return product
+}
+
+// BigLongMultiplication returns x * y computed with long multiplication.
+func BigLongMultiplication(x, y BigInt) BigInt {
+	return normalize(BigInt{x.negative != y.negative, multiplyDigits(x.digits, y.digits)})
+}
+
+// BigKaratsubaMultiplication returns x * y computed with Karatsuba
+// multiplication, which splits both numbers in two halves of m digits:
+// x*y = ac*10^2m + ((a+b)(c+d) - ac - bd)*10^m + bd.
+func BigKaratsubaMultiplication(x, y BigInt) BigInt {
+	return normalize(BigInt{x.negative != y.negative, karatsubaDigits(x.digits, y.digits)})
+}
+
+func karatsubaDigits(x, y []int) []int {
+	if len(x) < karatsubaCutoff || len(y) < karatsubaCutoff {
+		return multiplyDigits(x, y)
+	}
+	m := MaxBetween(len(x), len(y)) / 2
+	b, a := splitDigits(x, m)
+	d, c := splitDigits(y, m)
+
+	ac := BigInt{digits: karatsubaDigits(a, c)}
+	bd := BigInt{digits: karatsubaDigits(b, d)}
+	abcd := BigInt{digits: karatsubaDigits(addDigits(a, b), addDigits(c, d))}
+	middle := BigSubtract(BigSubtract(normalize(abcd), normalize(ac)), normalize(bd))
+
+	sum := BigAdd(shiftDigits(normalize(ac), 2*m), shiftDigits(middle, m))
+	return BigAdd(sum, normalize(bd)).digits
+}
+
+// splitDigits splits a digit array into its m least significant digits and
+// the rest.
+func splitDigits(x []int, m int) ([]int, []int) {
+	if len(x) <= m {
+		return x, nil
+	}
+	return x[:m], x[m:]
+}
+
+// shiftDigits multiplies x by 10^m.
+func shiftDigits(x BigInt, m int) BigInt {
+	if x.Sign() == 0 {
+		return x
+	}
+	return BigInt{x.negative, append(make([]int, m), x.digits...)}
+}
+
+// BigLongDivision divides x by y with long division and returns the quotient
+// and the remainder. Like LongDivision the quotient is truncated towards zero,
+// so the remainder has the sign of x.
+func BigLongDivision(x, y BigInt) (BigInt, BigInt, error) {
+	if y.Sign() == 0 {
+		return BigInt{}, BigInt{}, ErrDivisionByZero
+	}
+	divisor := y.Abs()
+	quotient := make([]int, len(x.digits))
+	remainder := BigInt{}
+	for i := len(x.digits) - 1; i >= 0; i-- {
+		remainder = BigAdd(shiftDigits(remainder, 1), NewBigInt(x.digits[i]))
+		digit := 0
+		for remainder.Cmp(divisor) >= 0 {
+			remainder = BigSubtract(remainder, divisor)
+			digit++
+		}
+		quotient[i] = digit
+	}
+	q := normalize(BigInt{x.negative != y.negative, quotient})
+	if x.negative {
+		remainder = remainder.Neg()
+	}
+	return q, remainder, nil
+}
+
+// BigPower returns base^power by repeated squaring.
+func BigPower(base BigInt, power int) (BigInt, error) {
+	if power < 0 {
+		return BigInt{}, ErrNegativePower
+	}
+	result := NewBigInt(1)
+	for power > 0 {
+		if power%2 == 1 {
+			result = BigKaratsubaMultiplication(result, base)
+		}
+		base = BigKaratsubaMultiplication(base, base)
+		power /= 2
+	}
+	return result, nil
+}
+
+// BigFactorial returns n! for n >= 0.
+func BigFactorial(n int) (BigInt, error) {
+	if n < 0 {
+		return BigInt{}, ErrNotWholeNumber
+	}
+	result := NewBigInt(1)
+	for i := 2; i <= n; i++ {
+		result = BigKaratsubaMultiplication(result, NewBigInt(i))
+	}
+	return result, nil
+}
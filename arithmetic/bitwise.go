@@ -0,0 +1,68 @@
+package arithmetic
+
+import (
+	"math/bits"
+)
+
+// BitwiseAnd returns the bits set in both x and y.
+func BitwiseAnd(x, y int) int {
+	return x & y
+}
+
+// BitwiseOr returns the bits set in either x or y.
+func BitwiseOr(x, y int) int {
+	return x | y
+}
+
+// BitwiseXor returns the bits set in exactly one of x and y.
+func BitwiseXor(x, y int) int {
+	return x ^ y
+}
+
+// BitwiseNot flips every bit of x.
+func BitwiseNot(x int) int {
+	return ^x
+}
+
+// ShiftLeft shifts the bits of x left by n places, filling with zeros.
+func ShiftLeft(x, n int) int {
+	return x << uint(n)
+}
+
+// ShiftRight shifts the bits of x right by n places. The sign bit is copied
+// into the vacated places, so negative numbers stay negative.
+func ShiftRight(x, n int) int {
+	return x >> uint(n)
+}
+
+// RotateLeft rotates the 64 bits of x left by n places. Bits shifted out on
+// the left come back in on the right.
+func RotateLeft(x, n int) int {
+	return int(bits.RotateLeft64(uint64(x), n))
+}
+
+// RotateRight rotates the 64 bits of x right by n places.
+func RotateRight(x, n int) int {
+	return int(bits.RotateLeft64(uint64(x), -n))
+}
+
+// RotateLeftWidth rotates the lowest width bits of x left by n places. The
+// result is sign extended from width bits.
+func RotateLeftWidth(x, n, width int) int {
+	mask := ^uint64(0) >> uint(64-width)
+	n %= width
+	if n < 0 {
+		n += width
+	}
+	pattern := uint64(x) & mask
+	rotated := (pattern<<uint(n) | pattern>>uint(width-n)) & mask
+	if rotated>>uint(width-1) == 1 {
+		rotated |= ^mask
+	}
+	return int(rotated)
+}
+
+// RotateRightWidth rotates the lowest width bits of x right by n places.
+func RotateRightWidth(x, n, width int) int {
+	return RotateLeftWidth(x, -(n % width), width)
+}
@@ -0,0 +1,192 @@
+package arithmetic
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+/**
+This file extends the square root, the exponent and the natural logarithm to
+complex numbers, together with the parts of a complex number: its conjugate,
+modulus and argument. The series are the same as for real numbers, evaluated
+with complex arithmetic.
+*/
+
+// ExactMargin is a margin of error below the rounding error of any float64.
+// With it HeronsSquareRoot and ComplexSquareRoot iterate until their guesses
+// stop improving, which is how the complex functions take their square roots.
+const ExactMargin = math.SmallestNonzeroFloat64
+
+// powerTerms is the number of terms of the logarithm and the exponent with
+// which ComplexPower computes e^(w ln z).
+const powerTerms = 30
+
+// maxExponentReductions bounds the halvings of reducedComplexExponent. e^z
+// overflows long before 2^1100.
+const maxExponentReductions = 1100
+
+// logReductions is the number of times the argument of the logarithm is
+// replaced by its square root, which halves its angle every time so that the
+// series converges quickly anywhere in the plane.
+const logReductions = 3
+
+// Conjugate returns the complex conjugate of z, a - bi for a + bi.
+func Conjugate(z complex128) complex128 {
+	return complex(real(z), -imag(z))
+}
+
+// Modulus returns |z|, the distance of z from 0.
+func Modulus(z complex128) float64 {
+	return math.Hypot(real(z), imag(z))
+}
+
+// Argument returns the angle of z from the positive real axis, between -pi and
+// pi.
+func Argument(z complex128) float64 {
+	return math.Atan2(imag(z), real(z))
+}
+
+// ComplexSquareRoot computes the principal square root of z, the one with a
+// non-negative real part. For z = a + bi the larger part of the root is the
+// real square root of (|z| + |a|)/2, computed with HeronsSquareRoot, and the
+// other one is b divided by twice that, which loses no digits to cancellation
+// when b is small. The root takes the sign of b, also of a negative zero, so
+// that the branch cut along the negative real axis is the usual one.
+func ComplexSquareRoot(z complex128, margin float64) (complex128, error) {
+	if !(margin > 0) {
+		return 0, ErrMarginOfError
+	}
+	larger, err := HeronsSquareRoot((Modulus(z)+math.Abs(real(z)))/2, margin)
+	if err != nil || larger == 0 {
+		return 0, err
+	}
+	smaller := math.Abs(imag(z)) / (2 * larger)
+	a, b := larger, smaller
+	if real(z) < 0 {
+		a, b = smaller, larger
+	}
+	if math.Signbit(imag(z)) {
+		b = -b
+	}
+	return complex(a, b), nil
+}
+
+// ComplexExponent computes e^z using the Taylor Series of Exponent expanded to
+// the n-th term.
+func ComplexExponent(z complex128, n int) complex128 {
+	term := z
+	sum := 1 + z
+	for k := 2; k < n+2; k++ {
+		term *= z / complex(float64(k), 0)
+		sum += term
+	}
+	return sum
+}
+
+// ComplexNaturalLog computes the principal value of ln(z), whose imaginary part
+// is between -pi and pi. It uses the series ln(z) = 2(y + y^3/3 + y^5/5 + ...)
+// with y = (z-1)/(z+1), expanded to n terms, which converges for z in the
+// right half-plane. z is first scaled by a power of 2 to a modulus between
+// sqrt(2)/2 and sqrt(2) and then brought close to 1 by taking square roots:
+//
+//	ln(z) = e*ln(2) + 2^k ln(sqrt^k(z / 2^e))
+//
+// and z in the left half-plane is reflected with ln(z) = ln(-z) +- pi*i.
+func ComplexNaturalLog(z complex128, n int) (complex128, error) {
+	if z == 0 || cmplx.IsNaN(z) {
+		return 0, ErrLogDomain
+	}
+	if real(z) < 0 {
+		ln, err := ComplexNaturalLog(-z, n)
+		if imag(z) < 0 {
+			return ln - complex(0, math.Pi), err
+		}
+		return ln + complex(0, math.Pi), err
+	}
+	mantissa, e := math.Frexp(Modulus(z))
+	if mantissa < math.Sqrt2/2 {
+		e--
+	}
+	z = complex(math.Ldexp(real(z), -e), math.Ldexp(imag(z), -e))
+	for k := 0; k < logReductions; k++ {
+		z, _ = ComplexSquareRoot(z, ExactMargin)
+	}
+	ln := complex(float64(int(1)<<logReductions), 0) * logSeriesComplex(z, n)
+	if e != 0 {
+		ln += complex(float64(e), 0) * logSeriesComplex(2, n)
+	}
+	return ln, nil
+}
+
+// logSeriesComplex expands ln(z) = 2(y + y^3/3 + y^5/5 + ...) with
+// y = (z-1)/(z+1) to n terms after y.
+func logSeriesComplex(z complex128, n int) complex128 {
+	y := (z - 1) / (z + 1)
+	ySquared := y * y
+	power := y
+	sum := y
+	for k := 1; k <= n; k++ {
+		power *= ySquared
+		sum += power / complex(float64(2*k+1), 0)
+	}
+	return 2 * sum
+}
+
+// ComplexLogBaseTen computes log(z) as ln(z) / ln(10).
+func ComplexLogBaseTen(z complex128, n int) (complex128, error) {
+	ln, err := ComplexNaturalLog(z, n)
+	if err != nil {
+		return 0, err
+	}
+	ln10, _ := ComplexNaturalLog(10, n)
+	return ln / ln10, nil
+}
+
+// ComplexPower computes the principal value of z^w. Whole powers are computed
+// by repeated squaring, the others as e^(w ln z) with ComplexNaturalLog and
+// ComplexExponent.
+func ComplexPower(z, w complex128) (complex128, error) {
+	if imag(w) == 0 && real(w) == math.Trunc(real(w)) && math.Abs(real(w)) < 1<<31 {
+		power := int(real(w))
+		if z == 0 && power < 0 {
+			return 0, ErrDivisionByZero
+		}
+		result := complex128(1)
+		for base, p := z, Abs(power); p > 0; p /= 2 {
+			if p%2 == 1 {
+				result *= base
+			}
+			base *= base
+		}
+		if power < 0 {
+			return 1 / result, nil
+		}
+		return result, nil
+	}
+	if z == 0 {
+		if real(w) > 0 {
+			return 0, nil
+		}
+		return 0, ErrDivisionByZero
+	}
+	ln, err := ComplexNaturalLog(z, powerTerms)
+	if err != nil {
+		return 0, err
+	}
+	return reducedComplexExponent(w * ln), nil
+}
+
+// reducedComplexExponent computes e^z as (e^(z/2^k))^(2^k), with k chosen so
+// that ComplexExponent only expands its series for a modulus of at most 1,
+// where powerTerms terms are exact.
+func reducedComplexExponent(z complex128) complex128 {
+	k := 0
+	for ; Modulus(z) > 1 && k < maxExponentReductions; k++ {
+		z /= 2
+	}
+	result := ComplexExponent(z, powerTerms)
+	for ; k > 0; k-- {
+		result *= result
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package arithmetic
+
+import (
+	"strconv"
+)
+
+/**
+This file contains the helpers used by long multiplication and Karatsuba
+multiplication to work on the digits of an int.
+*/
+
+// MakeIntArrayFromInt takes an int and returns an array of integer represen-
+// tation of the int where most significant bit is to the farthest right
+// position. 0th index always contains 0 as a dummy value.
+// i.e., 23 -> {0, 3, 2}
+func MakeIntArrayFromInt(x int) []int {
+	intArr := []int{0}
+	for x != 0 {
+		intArr = append(intArr, x%10)
+		x /= 10
+	}
+	return intArr
+}
+
+// MakeIntFromIntArray takes an array of integers and returns an int. The array
+// representation of the int has the most significant bit in its right most
+// index position. 0th index always contains 0 as a dummy value.
+// i.e., {0, 3, 2} -> 23
+func MakeIntFromIntArray(x []int) int {
+	number := 0
+	for i := len(x) - 1; i > 0; i-- {
+		number += x[i] * toThePowerInt(10, i-1)
+	}
+	return number
+}
+
+// CountNumDigits is a relatively slow implementation to count the number of
+// digits in an integer.
+func CountNumDigits(x int) (count int) {
+	if x == 0 {
+		return 1
+	}
+	for x != 0 {
+		x = x / 10
+		count++
+	}
+	return count
+}
+
+// CountNumDigitsFast is a faster implementation to count the number of digits
+// in an integer.
+func CountNumDigitsFast(x int) int {
+	stringOfX := strconv.Itoa(x)
+	return len(stringOfX)
+}
+
+// MaxBetween returns takes in 2 integers as input and returns the larger value.
+func MaxBetween(x, y int) int {
+	if x > y {
+		return x
+	}
+	return y
+}
@@ -0,0 +1,203 @@
+package arithmetic
+
+/**
+This file contains Fraction, an exact rational number, and its arithmetic. Its
+numerator and denominator are BigInts, so fractions never overflow. A fraction
+is always reduced with BigLongDivision by the greatest common divisor of its
+numerator and denominator, so 14/6 is kept as 7/3, and can be printed as a
+mixed number, e.g. 2 1/3.
+*/
+
+// maxFloatDigits is the number of digits beyond which a BigInt no longer fits
+// in a float64.
+const maxFloatDigits = 300
+
+// Fraction is a reduced fraction with a positive denominator. The zero value
+// is not valid, use NewFraction or NewBigFraction.
+type Fraction struct {
+	numerator   BigInt
+	denominator BigInt
+}
+
+// NewFraction returns numerator/denominator reduced to lowest terms.
+func NewFraction(numerator, denominator int) (Fraction, error) {
+	return NewBigFraction(NewBigInt(numerator), NewBigInt(denominator))
+}
+
+// NewBigFraction returns numerator/denominator reduced to lowest terms.
+func NewBigFraction(numerator, denominator BigInt) (Fraction, error) {
+	if denominator.Sign() == 0 {
+		return Fraction{}, ErrDivisionByZero
+	}
+	if denominator.Sign() < 0 {
+		numerator, denominator = numerator.Neg(), denominator.Neg()
+	}
+	numerator, denominator = reduce(numerator, denominator)
+	return Fraction{numerator, denominator}, nil
+}
+
+// GCD returns the greatest common divisor of x and y with Euclid's algorithm.
+// GCD(0, 0) is 1, so that it can always be divided by.
+func GCD(x, y int) int {
+	x, y = Abs(x), Abs(y)
+	for y != 0 {
+		quotient, _ := LongDivision(x, y)
+		x, y = y, x-quotient*y
+	}
+	if x == 0 {
+		return 1
+	}
+	return x
+}
+
+// BigGCD returns the greatest common divisor of x and y like GCD.
+func BigGCD(x, y BigInt) BigInt {
+	x, y = x.Abs(), y.Abs()
+	for y.Sign() != 0 {
+		_, remainder, _ := BigLongDivision(x, y)
+		x, y = y, remainder
+	}
+	if x.Sign() == 0 {
+		return NewBigInt(1)
+	}
+	return x
+}
+
+// Numerator returns the numerator of f, which carries its sign.
+func (f Fraction) Numerator() BigInt {
+	return f.numerator
+}
+
+// Denominator returns the denominator of f, which is always positive.
+func (f Fraction) Denominator() BigInt {
+	return f.denominator
+}
+
+// IsInteger reports whether f is a whole number.
+func (f Fraction) IsInteger() bool {
+	return isOne(f.denominator)
+}
+
+// Float returns f as a float. Numerators and denominators too long for a
+// float64 lose the same number of their last digits first, which leaves the
+// quotient unchanged as far as a float64 can tell.
+func (f Fraction) Float() float64 {
+	numerator, denominator := f.numerator, f.denominator
+	if excess := MaxBetween(len(numerator.digits), len(denominator.digits)) - maxFloatDigits; excess > 0 {
+		numerator, denominator = dropDigits(numerator, excess), dropDigits(denominator, excess)
+	}
+	return numerator.Float() / denominator.Float()
+}
+
+// Neg returns -f.
+func (f Fraction) Neg() Fraction {
+	return Fraction{f.numerator.Neg(), f.denominator}
+}
+
+// Abs returns |f|.
+func (f Fraction) Abs() Fraction {
+	return Fraction{f.numerator.Abs(), f.denominator}
+}
+
+// String returns f as numerator/denominator, e.g. 7/3, or as an integer.
+func (f Fraction) String() string {
+	if f.IsInteger() {
+		return f.numerator.String()
+	}
+	return f.numerator.String() + "/" + f.denominator.String()
+}
+
+// MixedString returns f as a mixed number, e.g. 2 1/3 for 7/3.
+func (f Fraction) MixedString() string {
+	whole, rest, _ := BigLongDivision(f.numerator, f.denominator)
+	switch {
+	case rest.Sign() == 0:
+		return whole.String()
+	case whole.Sign() == 0:
+		return f.String()
+	}
+	return whole.String() + " " + rest.Abs().String() + "/" + f.denominator.String()
+}
+
+// FractionAdd returns x + y.
+func FractionAdd(x, y Fraction) (Fraction, error) {
+	divisor := BigGCD(x.denominator, y.denominator)
+	xScale, _, _ := BigLongDivision(y.denominator, divisor)
+	yScale, _, _ := BigLongDivision(x.denominator, divisor)
+	numerator := BigAdd(BigKaratsubaMultiplication(x.numerator, xScale), BigKaratsubaMultiplication(y.numerator, yScale))
+	return NewBigFraction(numerator, BigKaratsubaMultiplication(x.denominator, xScale))
+}
+
+// FractionSubtract returns x - y.
+func FractionSubtract(x, y Fraction) (Fraction, error) {
+	return FractionAdd(x, y.Neg())
+}
+
+// FractionMultiply returns x * y. Both are cross-reduced first, so that the
+// products stay as small as possible and are already in lowest terms.
+func FractionMultiply(x, y Fraction) (Fraction, error) {
+	a, d := reduce(x.numerator, y.denominator)
+	c, b := reduce(y.numerator, x.denominator)
+	return Fraction{BigKaratsubaMultiplication(a, c), BigKaratsubaMultiplication(b, d)}, nil
+}
+
+// FractionDivide returns x / y.
+func FractionDivide(x, y Fraction) (Fraction, error) {
+	if y.numerator.Sign() == 0 {
+		return Fraction{}, ErrDivisionByZero
+	}
+	return FractionMultiply(x, y.inverse())
+}
+
+// FractionPower returns x^power. Negative powers invert x. The powers of a
+// numerator and a denominator without a common divisor have none either, so
+// they are raised separately with BigPower and need no reduction.
+func FractionPower(x Fraction, power int) (Fraction, error) {
+	if power < 0 {
+		if x.numerator.Sign() == 0 {
+			return Fraction{}, ErrDivisionByZero
+		}
+		x, power = x.inverse(), -power
+	}
+	numerator, err := BigPower(x.numerator, power)
+	if err != nil {
+		return Fraction{}, err
+	}
+	denominator, err := BigPower(x.denominator, power)
+	if err != nil {
+		return Fraction{}, err
+	}
+	return Fraction{numerator, denominator}, nil
+}
+
+// inverse returns 1/f for a non-zero f, keeping the denominator positive.
+func (f Fraction) inverse() Fraction {
+	if f.numerator.Sign() < 0 {
+		return Fraction{f.denominator.Neg(), f.numerator.Neg()}
+	}
+	return Fraction{f.denominator, f.numerator}
+}
+
+// reduce divides x and y by their greatest common divisor.
+func reduce(x, y BigInt) (BigInt, BigInt) {
+	divisor := BigGCD(x, y)
+	if isOne(divisor) {
+		return x, y
+	}
+	x, _, _ = BigLongDivision(x, divisor)
+	y, _, _ = BigLongDivision(y, divisor)
+	return x, y
+}
+
+// isOne reports whether x is 1.
+func isOne(x BigInt) bool {
+	return !x.negative && len(x.digits) == 1 && x.digits[0] == 1
+}
+
+// dropDigits returns x without its n least significant digits.
+func dropDigits(x BigInt, n int) BigInt {
+	if n >= len(x.digits) {
+		return BigInt{}
+	}
+	return normalize(BigInt{x.negative, x.digits[n:]})
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"math"
 	"time"
+
+	"calculator/arithmetic"
+	"calculator/trig"
 )
 
 // RunBenchmark runs benchmark code for arithmetic and trigonometry functions.
@@ -26,7 +29,7 @@ func BenchmarkArithmeticFunctions() {
 	Add(x, y)
 	fmt.Printf("%d + %d                              took %s\n", x, y, time.Now().Sub(start))
 	start = time.Now()
-	BitwiseAdd(x, y)
+	arithmetic.BitwiseAdd(x, y)
 	fmt.Printf("BitwiseAdd(%d, %d)                   took %s\n\n", x, y, time.Now().Sub(start))
 
 	// Compare subtract functions
@@ -34,7 +37,7 @@ func BenchmarkArithmeticFunctions() {
 	Subtract(x, y)
 	fmt.Printf("%d - %d                              took %s\n", x, y, time.Now().Sub(start))
 	start = time.Now()
-	BitwiseSubtractFast(x, y)
+	arithmetic.BitwiseSubtractFast(x, y)
 	fmt.Printf("BitwiseSubtractFast(%d, %d)          took %s\n\n", x, y, time.Now().Sub(start))
 
 	// Compare multiply functions
@@ -42,10 +45,10 @@ func BenchmarkArithmeticFunctions() {
 	Multiply(x, y)
 	fmt.Printf("%d * %d                              took %s\n", x, y, time.Now().Sub(start))
 	start = time.Now()
-	LongMultiplication(x, y)
+	arithmetic.LongMultiplication(x, y)
 	fmt.Printf("LongMultiplication(%d, %d)           took %s\n", x, y, time.Now().Sub(start))
 	start = time.Now()
-	KaratsubaMultiplicationFast(x, y)
+	arithmetic.KaratsubaMultiplicationFast(x, y)
 	fmt.Printf("KaratsubaMultiplicationFast(%d, %d)  took %s\n\n", x, y, time.Now().Sub(start))
 
 	// Compare division functions
@@ -53,7 +56,7 @@ func BenchmarkArithmeticFunctions() {
 	Divide(y, x)
 	fmt.Printf("%d / %d                              took %s\n", y, x, time.Now().Sub(start))
 	start = time.Now()
-	LongDivision(y, x)
+	arithmetic.LongDivision(y, x)
 	fmt.Printf("LongDivision(%d, %d)                 took %s\n\n", y, x, time.Now().Sub(start))
 }
 
@@ -72,7 +75,7 @@ func BenchMarkTrigonometryFunctions() {
 	math.Sin(x)
 	fmt.Printf("math.Sin(%.2f)      took %s\n", x, time.Now().Sub(start))
 	start = time.Now()
-	Sine(x, accuracy)
+	trig.Sine(x, accuracy)
 	fmt.Printf("Sine(%.2f)          took %s\n\n", x, time.Now().Sub(start))
 
 	// Compare arcsin(x) functions
@@ -80,7 +83,7 @@ func BenchMarkTrigonometryFunctions() {
 	math.Asin(x)
 	fmt.Printf("math.Asin(%.2f)     took %s\n", x, time.Now().Sub(start))
 	start = time.Now()
-	InverseSine(x, accuracy)
+	trig.InverseSine(x, accuracy)
 	fmt.Printf("InverseSine(%.2f)   took %s\n\n", x, time.Now().Sub(start))
 
 	// Compare cos(x) functions
@@ -88,7 +91,7 @@ func BenchMarkTrigonometryFunctions() {
 	math.Cos(x)
 	fmt.Printf("math.Cos(%.2f)      took %s\n", x, time.Now().Sub(start))
 	start = time.Now()
-	Cosine(x, accuracy)
+	trig.Cosine(x, accuracy)
 	fmt.Printf("Cosine(%.2f)        took %s\n\n", x, time.Now().Sub(start))
 
 	// Compare arccos(x) functions
@@ -96,7 +99,7 @@ func BenchMarkTrigonometryFunctions() {
 	math.Acos(x)
 	fmt.Printf("math.arccos(%.2f)   took %s\n", x, time.Now().Sub(start))
 	start = time.Now()
-	InverseCosine(x, accuracy)
+	trig.InverseCosine(x, accuracy)
 	fmt.Printf("InverseCosine(%.2f) took %s\n\n", x, time.Now().Sub(start))
 
 	// Compare tan(x) functions
@@ -104,7 +107,7 @@ func BenchMarkTrigonometryFunctions() {
 	math.Tan(x)
 	fmt.Printf("math.tan(%.2f)      took %s\n", x, time.Now().Sub(start))
 	start = time.Now()
-	Tangent(x, accuracy)
+	trig.Tangent(x, accuracy)
 	fmt.Printf("Tangent(%.2f)       took %s\n\n", x, time.Now().Sub(start))
 
 	// Compare arctan(x) functions
@@ -112,6 +115,6 @@ func BenchMarkTrigonometryFunctions() {
 	math.Atan(x)
 	fmt.Printf("math.arctan(%.2f)   took %s\n", x, time.Now().Sub(start))
 	start = time.Now()
-	InverseTangent(x, accuracy)
+	trig.InverseTangent(x, accuracy)
 	fmt.Printf("InverseTanget(%.2f) took %s\n\n", x, time.Now().Sub(start))
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"calculator/arithmetic"
+)
+
+/**
+This file contains the big integer mode, toggled with bigint on|off. In this
+mode integer arithmetic is computed on arrays of decimal digits, so that
+integers no longer overflow: + - * / ^ and factorial work on integers of any
+length, and integer literals of any length can be typed, e.g.
+
+    >bigint on
+    >factorial(200)
+    >2^200 / 3^50
+
+Results that fit in an int are kept as ints, so the rest of the calculator,
+such as the bitwise operators, keeps working on them. A word size takes
+precedence over the big integer mode.
+*/
+
+// Limits of the computations whose time grows with the number of digits of the
+// result. Larger factorials and powers are rejected instead of blocking the
+// calculator.
+const (
+	// MaxBigFactorial is the largest n whose factorial is computed.
+	MaxBigFactorial = 3000
+	// MaxExponent is the largest power of an integer that is computed.
+	MaxExponent = 10000
+	// MaxPowerDigits is the largest number of digits a power may have, as
+	// estimated from the digits of its base times the exponent.
+	MaxPowerDigits = 20000
+)
+
+// BigValue wraps a BigInt in a Value. Integers that fit in an int are returned
+// as ints.
+func BigValue(x arithmetic.BigInt) Value {
+	if n, ok := x.Int(); ok {
+		return IntValue(n)
+	}
+	return Value{Kind: BigKind, Big: x}
+}
+
+// AsBigInt returns the value of v as a BigInt if v holds an integer.
+func (v Value) AsBigInt() (arithmetic.BigInt, bool) {
+	switch v.Kind {
+	case IntKind:
+		return arithmetic.NewBigInt(v.Int), true
+	case BigKind:
+		return v.Big, true
+	}
+	return arithmetic.BigInt{}, false
+}
+
+// BigIntsEnabled reports whether integer arithmetic is computed on big
+// integers. Word sizes take precedence.
+func BigIntsEnabled() bool {
+	return CurrentSession.BigInts && !CurrentSession.Word.Enabled()
+}
+
+// applyBigOperator computes x op y on big integers. Like for ints, division
+// truncates towards zero and negative powers are computed in floating point.
+func applyBigOperator(op string, x, y arithmetic.BigInt) (Value, error) {
+	switch op {
+	case "+":
+		return BigValue(arithmetic.BigAdd(x, y)), nil
+	case "-":
+		return BigValue(arithmetic.BigSubtract(x, y)), nil
+	case "*":
+		return BigValue(arithmetic.BigKaratsubaMultiplication(x, y)), nil
+	case "/":
+		quotient, _, err := arithmetic.BigLongDivision(x, y)
+		return BigValue(quotient), err
+	case "^":
+		power, ok := y.Int()
+		if !ok || power > MaxExponent {
+			return Value{}, fmt.Errorf("power is too large, y must be at most %d", MaxExponent)
+		}
+		if power < 0 {
+			return FloatValue(math.Pow(x.Float(), float64(power))), nil
+		}
+		if err := checkPowerDigits(x, power); err != nil {
+			return Value{}, err
+		}
+		v, err := arithmetic.BigPower(x, power)
+		return BigValue(v), err
+	}
+	return Value{}, fmt.Errorf("unknown operator %s", op)
+}
+
+// checkPowerDigits rejects x^power when the result could have more than
+// MaxPowerDigits digits, e.g. (10^10000)^1000, whose exponent alone is small.
+func checkPowerDigits(x arithmetic.BigInt, power int) error {
+	if x.NumDigits() > MaxPowerDigits/arithmetic.MaxBetween(power, 1) {
+		return fmt.Errorf("power is too large, the result would have more than %d digits", MaxPowerDigits)
+	}
+	return nil
+}
+
+// ExecuteBigIntCommand shows whether the big integer mode is on, or switches
+// it when on or off is given.
+func ExecuteBigIntCommand(args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: bigint on|off")
+	}
+	if len(args) == 1 {
+		on, err := ParseOnOff(args[0])
+		if err != nil {
+			return err
+		}
+		CurrentSession.BigInts = on
+	}
+	state := "off"
+	if CurrentSession.BigInts {
+		state = "on"
+	}
+	fmt.Printf("Big integers: %s.\n", state)
+	return nil
+}
+
+// ParseOnOff reads on or off.
+func ParseOnOff(s string) (bool, error) {
+	switch s {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected on or off, got %s", s)
+}
@@ -8,21 +8,80 @@ import (
 )
 
 func main() {
-	PrintWelcomeHeader()
-	PrintHelp()
+	for _, err := range LoadExternalPlugins() {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+	}
+	if len(os.Args) > 1 {
+		os.Exit(RunCommandLine(os.Args[1:]))
+	}
+	CurrentSession.Interactive = IsTerminal(os.Stdin)
+	if CurrentSession.Interactive {
+		PrintWelcomeHeader()
+		PrintHelp()
+		history, err := LoadHistory()
+		if err != nil {
+			fmt.Printf("WARNING: history will not be saved: %s\n", err)
+		}
+		CurrentSession.History = history
+	}
+	os.Exit(RunPrompt(bufio.NewReader(os.Stdin)))
+}
+
+// RunPrompt executes the lines read from reader at the main prompt until the
+// input ends, and returns ExitFailure if any of them failed and ExitOk
+// otherwise.
+func RunPrompt(reader *bufio.Reader) int {
 	for {
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Println("Please enter the name of function you would like to use.")
-		fmt.Print(">")
-		input, _ := reader.ReadString('\n')
-		fmt.Println(input)
-		ParseAndExecute(input, reader)
+		if CurrentSession.Interactive {
+			fmt.Println("Please enter the name of function you would like to use.")
+		}
+		prompt := ">"
+		if CurrentSession.RPN {
+			prompt = "rpn>"
+		}
+		input, readErr := ReadLine(reader, prompt)
+		if CurrentSession.Interactive {
+			fmt.Println(input)
+		}
+		if err := ExecuteHistoryLine(input, reader); err != nil {
+			CurrentSession.ReportError(input, err)
+		}
+		if readErr != nil {
+			return CurrentSession.ExitStatus()
+		}
+	}
+}
+
+// ExecuteHistoryLine expands references to the history such as !! in a line
+// typed at the main prompt, records the line in the history and executes it.
+func ExecuteHistoryLine(input string, reader *bufio.Reader) error {
+	expanded, err := CurrentSession.History.Expand(input)
+	if err != nil {
+		return err
 	}
+	if expanded != input {
+		fmt.Println(expanded)
+	}
+	CurrentSession.History.Add(expanded)
+	return ParseAndExecute(expanded, reader)
 }
 
-// ParseAndExecute routes user input to appropriate function handlers.
-func ParseAndExecute(input string, reader *bufio.Reader) {
-	input = strings.TrimSpace(strings.ToLower(input))
+// ParseAndExecute routes user input to appropriate function handlers. Input
+// that is not the name of a function is evaluated as an infix expression, or as
+// RPN when RPN mode is on. Anything after a # is a comment and is ignored.
+func ParseAndExecute(input string, reader *bufio.Reader) error {
+	command := strings.TrimSpace(StripComment(input))
+	input = strings.ToLower(command)
+	CurrentSession.Flags = WordFlags{}
+	if CurrentSession.RPN {
+		switch input {
+		case "", "rpn", "exit", "h", "help", "history", "deg", "rad", "grad":
+		default:
+			if !IsRPNPassthroughCommand(input) {
+				return ExecuteRPNLine(input)
+			}
+		}
+	}
 	switch input {
 	case "tests", "test", "t":
 		RunTests()
@@ -30,21 +89,32 @@ func ParseAndExecute(input string, reader *bufio.Reader) {
 		RunBenchmark()
 	case "h", "help":
 		PrintHelp()
-	case "add", "+", "subtract", "-", "divide", "/", "multiply", "*", "permutation", "p", "combination", "c", "pow":
-		PromptBasicArithmeticValuesAndCompute(input, reader)
-	case "factorial", "!", "abs":
-		PromptBasicArithmeticForSingleInput(input, reader)
-	case "ln", "log", "exponent", "e", "sqrt":
-		PromptComplexArithmetic(input, reader)
-	case "sin", "arcsin", "cos", "arccos", "tan", "arctan":
-		PromptTrigValuesAndCompute(input, reader)
-	case "min", "max", "mean", "sd", "standard deviation", "mode", "median", "sum":
-		PromptDefaultStatValuesAndCompute(input, reader)
-	case "probability density function", "pdf":
-		PromptPdfStatValuesAndCompute(input, reader)
+	case "history":
+		CurrentSession.History.Print()
+	case "rpn":
+		CurrentSession.ToggleRPN()
+	case "deg", "rad", "grad":
+		CurrentSession.SetAngleUnit(AngleUnit(input))
+	case "vars":
+		CurrentSession.PrintVariables()
+	case "funcs", "functions":
+		PrintFunctions(CurrentSession.Scope)
+	case "clear":
+		CurrentSession.ClearVariables()
+		fmt.Println("All variables cleared.")
 	case "exit":
-		os.Exit(3)
+		os.Exit(CurrentSession.ExitStatus())
+	case "":
+	default:
+		if f, ok := LookupFunction(input); ok && !f.ExpressionOnly {
+			return PromptFunctionAndCompute(f, reader)
+		}
+		if handled, err := ExecuteSessionCommand(command); handled {
+			return err
+		}
+		return EvaluateAndPrintExpression(input)
 	}
+	return nil
 }
 
 // PrintWelcomeHeader prints a pretty welcome header.
@@ -65,22 +135,45 @@ func PrintHelp() {
 	fmt.Println("===============================================================")
 	fmt.Println("|                            Help                             |")
 	fmt.Println("===============================================================")
-	fmt.Println("| 1. Arithmetic Functions:                                    |")
-	fmt.Println("|    * add (+)       * sqrt        * factorial (!)            |")
-	fmt.Println("|    * subtract (-)  * abs         * permutation (P)          |")
-	fmt.Println("|    * divide (/)    * pow         * combination (C)          |")
-	fmt.Println("|    * multiply (*)  * ln          * exponent (e)             |")
-	fmt.Println("|                    * log                                    |")
+	for i, category := range Categories {
+		fmt.Printf("| %-60s|\n", fmt.Sprintf("%d. %s:", i+1, category.Title))
+		PrintFunctionList(category.Name)
+		if category.Name == TrigCategory {
+			fmt.Println("|    Angle mode: [deg]  [rad]  [grad]                         |")
+			fmt.Println("|    e.g. sin(30deg)    arcsin(0.5) -> deg                    |")
+		}
+		fmt.Println("===============================================================")
+	}
+	fmt.Println("| 4. Expressions:                                             |")
+	fmt.Println("|    e.g. 3 + 4 * sin(0.25) - 2^5 / 7      mean([1, 2, 3])    |")
+	fmt.Println("|    x = sin(0.5)    ans    [vars]    [clear]                 |")
+	fmt.Println("|    f(x, y) = sqrt(x^2 + y^2)    [funcs]    [del name]       |")
+	fmt.Println("|    [source file]    # comment    [history]    !!    !n      |")
+	fmt.Println("|    [format fix|sig|sci|eng|auto digits]   e.g. format sci 3 |")
+	fmt.Println("|    [digits N|off]  series to N digits, e.g. sin(1, 40)      |")
+	fmt.Println("|    [frac on|mixed|off]  exact fractions, e.g. 7/3 -> dec    |")
+	fmt.Println("|    [complex rect|polar]  e.g. sqrt(-4)  (3+4i) * i  conj(z) |")
+	fmt.Println("|    [inspect] or [inspect x] shows the IEEE-754 bits of x    |")
+	fmt.Println("|    [output text|json] prints results as text or JSON        |")
+	fmt.Println("===============================================================")
+	fmt.Println("| 5. Memory:                                                  |")
+	fmt.Println("|    [m+]  [m-]  [mr]  [mc]  optionally followed by a name    |")
+	fmt.Println("|    mr and mr(name) can be used in expressions and prompts   |")
 	fmt.Println("===============================================================")
-	fmt.Println("| 2. Trigonometry Functions:                                  |")
-	fmt.Println("|    * sin           * cos         * tan                      |")
-	fmt.Println("|    * arcsin        * arccos      * arctan                   |")
+	fmt.Println("| 6. RPN Mode: [rpn] to toggle, e.g. 3 4 + 2 *    0.25 sin    |")
+	fmt.Println("|    [dup]  [swap]  [drop]  [roll]  [clear]                   |")
 	fmt.Println("===============================================================")
-	fmt.Println("| 3. Statistical Functions:                                   |")
-	fmt.Println("|    * min           * mode        * standard deviation (sd)  |")
-	fmt.Println("|    * max           * median      * probability density      |")
-	fmt.Println("|    * mean          * sum           function (pdf)           |")
+	fmt.Println("| 7. Programmer Mode: [base dec|hex|oct|bin|all]              |")
+	fmt.Println("|    0xff  0b1010  0o17    and or xor not shl shr rol ror     |")
+	fmt.Println("|    e.g. 0xff & ~0b1010 | 1 << 4                             |")
+	fmt.Println("|    [word int8..int64|uint8..uint64|off]  wraps integers     |")
+	fmt.Println("|    [bigint on|off]  integers of any length, e.g. 2^200      |")
 	fmt.Println("===============================================================")
+	if functions := FunctionsInCategory(PluginCategory); len(functions) > 0 {
+		fmt.Println("| 8. Plugin Functions:                                        |")
+		PrintFunctionList(PluginCategory)
+		fmt.Println("===============================================================")
+	}
 	fmt.Println("|    [help/h]    [tests/t]    [benchmark/bm]    [exit]        |")
 	fmt.Println("===============================================================")
 }
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/**
+This file contains the one-shot command-line mode, e.g.
+
+    calculator sin 0.25 --terms 9
+    calculator mean 1,2,3,4
+    calculator eval "2*pi"
+    calculator exponent 50 --format sci --digits 3
+    calculator sin 0.25 --terms 9 --json
+
+which computes a single result, prints it and exits. The exit status is 0 on
+success, 1 when the computation failed and 2 when the command was misused.
+*/
+
+// Exit statuses returned by RunCommandLine.
+const (
+	ExitOk         = 0
+	ExitFailure    = 1
+	ExitUsageError = 2
+)
+
+const commandLineName = "calculator"
+
+// RunCommandLine runs the command given as program arguments and returns the
+// exit status.
+func RunCommandLine(args []string) int {
+	args = moveLeadingFlags(args)
+	command := strings.ToLower(args[0])
+	switch command {
+	case "tests", "test", "t":
+		RunTests()
+		return ExitOk
+	case "bm", "benchmark":
+		RunBenchmark()
+		return ExitOk
+	case "h", "help", "-h", "--help":
+		PrintCommandLineUsage(os.Stdout)
+		return ExitOk
+	case "eval":
+		return RunEvalCommand(args[1:])
+	case "run":
+		return RunScriptCommand(args[1:])
+	case "inspect":
+		return RunInspectCommand(args[1:])
+	case "serve":
+		return RunServeCommand(args[1:])
+	case "rpc":
+		return RunRPCCommand(args[1:])
+	}
+
+	f, ok := LookupFunction(command)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: unknown command %s\n", commandLineName, args[0])
+		PrintCommandLineUsage(os.Stderr)
+		return ExitUsageError
+	}
+	return RunFunctionCommand(f, args[1:])
+}
+
+// RunFunctionCommand parses the arguments of a function subcommand, computes
+// the result and prints it. Required parameters are positional arguments, a
+// data parameter a comma separated list, and optional parameters are flags.
+func RunFunctionCommand(f *Function, args []string) int {
+	fs := flag.NewFlagSet(commandLineName+" "+f.Name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s\n", commandLineName, FunctionUsage(f))
+		fs.PrintDefaults()
+	}
+	optional := f.OptionalParams()
+	flagValues := make([]string, len(optional))
+	for i, p := range optional {
+		fs.StringVar(&flagValues[i], p.Flags[0], p.DefaultString(), p.Description)
+		for _, alias := range p.Flags[1:] {
+			fs.StringVar(&flagValues[i], alias, p.DefaultString(), "shorthand for --"+p.Flags[0])
+		}
+	}
+
+	applySettings := addSettingFlags(fs)
+
+	positional, err := ParseInterleavedFlags(fs, args)
+	if err != nil {
+		return ExitUsageError
+	}
+	if err := applySettings(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, err)
+		return ExitUsageError
+	}
+	required := f.RequiredParams()
+	if len(required) == 1 && required[0].Kind == DataParam && len(positional) > 1 {
+		positional = []string{strings.Join(positional, ",")}
+	}
+	if len(positional) != len(required) {
+		fmt.Fprintf(os.Stderr, "%s: %s expects %s, got %d arguments\n", commandLineName, f.Name, paramNames(required), len(positional))
+		fs.Usage()
+		return ExitUsageError
+	}
+
+	record := ResultRecord{Function: f.Name, Inputs: make(map[string]interface{})}
+	values := make([]Value, 0, len(f.Params))
+	for i, p := range required {
+		input := positional[i]
+		if p.Kind == DataParam {
+			input = "[" + input + "]"
+		}
+		v, err := EvaluateExpression(input, NewScope())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: invalid %s: %s\n", commandLineName, p.Name, err)
+			return ExitUsageError
+		}
+		values = append(values, v)
+		record.Inputs[p.Name] = JSONValue(v)
+	}
+	if len(optional) > 0 {
+		record.Params = make(map[string]interface{})
+	}
+	for i, p := range optional {
+		if !p.Kind.Accepts(flagValues[i]) {
+			fmt.Fprintf(os.Stderr, "%s: invalid --%s: expected %s, got %q\n", commandLineName, p.Flags[0], p.Kind, flagValues[i])
+			return ExitUsageError
+		}
+		v := p.Kind.Parse(flagValues[i])
+		values = append(values, v)
+		record.Params[p.Name] = JSONValue(v)
+	}
+
+	v, err := f.Invoke(values)
+	return PrintCommandLineResult(record, v, err)
+}
+
+// FunctionUsage returns the arguments of a function subcommand, e.g.
+// sin x [--terms n].
+func FunctionUsage(f *Function) string {
+	usage := f.Name
+	if required := f.RequiredParams(); len(required) > 0 {
+		usage += " " + paramNames(required)
+	}
+	for _, p := range f.OptionalParams() {
+		usage += fmt.Sprintf(" [--%s %s]", p.Flags[0], p.Name)
+	}
+	return usage
+}
+
+func paramNames(params []Param) string {
+	names := make([]string, len(params))
+	for i, p := range params {
+		names[i] = p.Name
+	}
+	return strings.Join(names, " ")
+}
+
+// moveLeadingFlags moves the setting flags given before the command, e.g.
+// calculator --json sin 0.25, behind it, where every command parses them.
+func moveLeadingFlags(args []string) []string {
+	fs := flag.NewFlagSet(commandLineName, flag.ContinueOnError)
+	addSettingFlags(fs)
+	end := leadingFlagsEnd(fs, args)
+	if end == 0 || end >= len(args) {
+		return args
+	}
+	moved := append([]string{args[end]}, args[:end]...)
+	return append(moved, args[end+1:]...)
+}
+
+// leadingFlagsEnd returns the index of the first argument that is neither a
+// flag starting with -- nor the value of one.
+func leadingFlagsEnd(fs *flag.FlagSet, args []string) int {
+	i := 0
+	for i < len(args) && strings.HasPrefix(args[i], "--") && args[i] != "--" {
+		name := strings.TrimPrefix(args[i], "--")
+		i++
+		if !strings.Contains(name, "=") && !isBoolFlag(fs, name) && i < len(args) {
+			i++
+		}
+	}
+	return i
+}
+
+// isBoolFlag reports whether the flag called name takes no value, like
+// --json. Unknown flags are left for fs to report.
+func isBoolFlag(fs *flag.FlagSet, name string) bool {
+	f := fs.Lookup(name)
+	if f == nil {
+		return true
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// parseLeadingFlags parses the flags before the first argument that is not a
+// flag, or before --, and returns the arguments after them. Flags must start
+// with --, so that an expression such as -128/-1 is never read as a flag.
+func parseLeadingFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	end := leadingFlagsEnd(fs, args)
+	if err := fs.Parse(args[:end]); err != nil {
+		return nil, err
+	}
+	args = args[end:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	return args, nil
+}
+
+// RunEvalCommand evaluates an expression given on the command line. Flags
+// must come before the expression and start with --, so that an expression
+// such as -pi is not mistaken for a flag.
+func RunEvalCommand(args []string) int {
+	fs := flag.NewFlagSet(commandLineName+" eval", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s eval [--format style] [--digits n] [--precision n] [--base base] [--word size] [--bigint] [--frac mode] [--json] <expression>\n", commandLineName)
+		fs.PrintDefaults()
+	}
+	applySettings := addSettingFlags(fs)
+	args, err := parseLeadingFlags(fs, args)
+	if err != nil {
+		return ExitUsageError
+	}
+	if err := applySettings(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, err)
+		return ExitUsageError
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return ExitUsageError
+	}
+	expression := strings.Join(args, " ")
+	v, err := EvaluateExpression(strings.ToLower(expression), NewScope())
+	return PrintCommandLineResult(ResultRecord{Expression: expression}, v, err)
+}
+
+// RunInspectCommand shows the IEEE-754 representation of an expression given
+// on the command line. Flags come before the expression, as for eval.
+func RunInspectCommand(args []string) int {
+	fs := flag.NewFlagSet(commandLineName+" inspect", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s inspect [--format style] [--digits n] [--precision n] [--base base] [--word size] [--bigint] [--frac mode] [--json] <expression>\n", commandLineName)
+		fs.PrintDefaults()
+	}
+	applySettings := addSettingFlags(fs)
+	args, err := parseLeadingFlags(fs, args)
+	if err != nil {
+		return ExitUsageError
+	}
+	if err := applySettings(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, err)
+		return ExitUsageError
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return ExitUsageError
+	}
+	if err := ExecuteInspectCommand(args); err != nil {
+		if JSONOutputEnabled() {
+			PrintResultRecord(ResultRecord{Expression: strings.Join(args, " "), Error: err.Error()})
+			return ExitFailure
+		}
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, err)
+		return ExitFailure
+	}
+	return ExitOk
+}
+
+// RunScriptCommand executes a script given on the command line.
+func RunScriptCommand(args []string) int {
+	fs := flag.NewFlagSet(commandLineName+" run", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s run [--keep-going] [--format style] [--digits n] [--precision n] [--base base] [--word size] [--bigint] [--frac mode] [--json] <file>\n", commandLineName)
+		fs.PrintDefaults()
+	}
+	keepGoing := false
+	fs.BoolVar(&keepGoing, "keep-going", false, "continue past lines that fail")
+	fs.BoolVar(&keepGoing, "k", false, "shorthand for --keep-going")
+	applySettings := addSettingFlags(fs)
+	positional, err := ParseInterleavedFlags(fs, args)
+	if err != nil {
+		return ExitUsageError
+	}
+	if err := applySettings(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, err)
+		return ExitUsageError
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return ExitUsageError
+	}
+	if err := RunScript(positional[0], keepGoing); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, err)
+		return ExitFailure
+	}
+	return ExitOk
+}
+
+// addSettingFlags adds the --format, --digits, --precision, --base, --word,
+// --bigint, --frac and --json flags to fs. The returned function applies them to the session
+// once the flags are parsed.
+func addSettingFlags(fs *flag.FlagSet) func() error {
+	style := ""
+	digits := -1
+	precision := ""
+	base := ""
+	word := ""
+	jsonOutput := false
+	bigInts := false
+	fractions := ""
+	fs.StringVar(&style, "format", "", "number format `style`: fix, sig, sci, eng or auto")
+	fs.IntVar(&digits, "digits", -1, "number of `digits` shown by the number format")
+	fs.StringVar(&precision, "precision", "", "number of `digits` series are computed to, or off")
+	fs.StringVar(&base, "base", "", "`base` of integer results: dec, hex, oct, bin or all")
+	fs.StringVar(&word, "word", "", "word `size` integers wrap to, e.g. int16 or uint8")
+	fs.BoolVar(&bigInts, "bigint", false, "compute with integers of any length")
+	fs.StringVar(&fractions, "frac", "", "fraction `mode` of integer division: on, mixed or off")
+	fs.BoolVar(&jsonOutput, "json", false, "print results as JSON objects")
+	return func() error {
+		if bigInts {
+			CurrentSession.BigInts = true
+		}
+		if fractions != "" {
+			if err := setFractionMode(fractions); err != nil {
+				return err
+			}
+		}
+		if jsonOutput {
+			CurrentSession.Output = JSONOutput
+		}
+		if precision != "" {
+			digits, err := ParsePrecision(precision)
+			if err != nil {
+				return err
+			}
+			CurrentSession.Precision = digits
+		}
+		if word != "" {
+			w, err := ParseWordSize(word)
+			if err != nil {
+				return err
+			}
+			CurrentSession.Word = w
+		}
+		if base != "" {
+			b, err := ParseNumberBase(base)
+			if err != nil {
+				return err
+			}
+			CurrentSession.Base = b
+		}
+		if style == "" && digits < 0 {
+			return nil
+		}
+		if style == "" {
+			style = string(CurrentSession.Format.Style)
+		}
+		fields := []string{style}
+		if digits >= 0 {
+			fields = append(fields, strconv.Itoa(digits))
+		}
+		f, err := ParseNumberFormat(fields)
+		if err != nil {
+			return err
+		}
+		CurrentSession.Format = f
+		return nil
+	}
+}
+
+// PrintCommandLineResult prints the result, or the error to stderr, and
+// returns the matching exit status. In JSON output mode the result or the
+// error is added to record, which is printed to stdout.
+func PrintCommandLineResult(record ResultRecord, v Value, err error) int {
+	if JSONOutputEnabled() {
+		if err != nil {
+			record.Error = err.Error()
+			PrintResultRecord(record)
+			return ExitFailure
+		}
+		record.SetResult(v)
+		PrintResultRecord(record)
+		return ExitOk
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, err)
+		return ExitFailure
+	}
+	fmt.Println(v)
+	if flags := CurrentSession.Flags.String(); flags != "" {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, flags)
+	}
+	return ExitOk
+}
+
+// ParseInterleavedFlags parses flags that may appear before, between or after
+// positional arguments and returns the positional arguments. Negative numbers
+// are treated as positional arguments rather than flags.
+func ParseInterleavedFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	for len(args) > 0 {
+		if IsFloat(args[0]) {
+			positional = append(positional, args[0])
+			args = args[1:]
+			continue
+		}
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+	return positional, nil
+}
+
+// PrintCommandLineUsage lists every subcommand.
+func PrintCommandLineUsage(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s                       start the interactive calculator\n", commandLineName)
+	fmt.Fprintf(w, "       %s <command> [args]      compute a single result and exit\n\n", commandLineName)
+	fmt.Fprintln(w, "commands:")
+	for _, f := range FunctionRegistry {
+		fmt.Fprintf(w, "  %s\n", FunctionUsage(f))
+	}
+	fmt.Fprintln(w, "  eval <expression>")
+	fmt.Fprintln(w, "  inspect <expression>")
+	fmt.Fprintln(w, "  serve [--addr host:port]")
+	fmt.Fprintln(w, "  rpc")
+	fmt.Fprintln(w, "  run [--keep-going] <file>")
+	fmt.Fprintln(w, "  tests")
+	fmt.Fprintln(w, "  benchmark")
+	fmt.Fprintln(w, "\nevery command except tests and benchmark accepts, also before the command:")
+	fmt.Fprintln(w, "  --format fix|sig|sci|eng|auto    number format of the result")
+	fmt.Fprintln(w, "  --digits n                       digits shown by the number format")
+	fmt.Fprintln(w, "  --precision n|off                digits series are computed to")
+	fmt.Fprintln(w, "  --base dec|hex|oct|bin|all       base of integer results")
+	fmt.Fprintln(w, "  --word int8..int64|uint8..uint64 word size integers wrap to")
+	fmt.Fprintln(w, "  --bigint                         compute with integers of any length")
+	fmt.Fprintln(w, "  --frac on|mixed|off              exact fractions for integer division")
+	fmt.Fprintln(w, "  --json                           print results as JSON objects")
+}
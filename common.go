@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"math"
 	"strconv"
@@ -12,64 +13,32 @@ This file contains all the common functions shared across components in this
 package.
 */
 
-// MakeIntArrayFromInt takes an int and returns an array of integer represen-
-// tation of the int where most significant bit is to the farthest right
-// position. 0th index always contains 0 as a dummy value.
-// i.e., 23 -> {0, 3, 2}
-func MakeIntArrayFromInt(x int) []int {
-	intArr := []int{0}
-	for x != 0 {
-		intArr = append(intArr, x%10)
-		x /= 10
-	}
-	return intArr
-}
-
-// MakeIntFromIntArray takes an array of integers and returns an int. The array
-// representation of the int has the most significant bit in its right most
-// index position. 0th index always contains 0 as a dummy value.
-// i.e., {0, 3, 2} -> 23
-func MakeIntFromIntArray(x []int) int {
-	number := 0
-	for i := len(x) - 1; i > 0; i-- {
-		number += x[i] * ToThePowerInt(10, i-1)
-	}
-	return number
-}
-
-// CountNumDigits is a relatively slow implementation to count the number of
-// digits in an integer.
-func CountNumDigits(x int) (count int) {
-	if x == 0 {
-		return 1
-	}
-	for x != 0 {
-		x = x / 10
-		count++
-	}
-	return count
-}
-
-// CountNumDigitsFast is a faster implementation to count the number of digits
-// in an integer.
-func CountNumDigitsFast(x int) int {
-	stringOfX := strconv.Itoa(x)
-	return len(stringOfX)
+// PrintRetryPrompt is used in UI code to tell the user to renter a value for a
+// function input.
+func PrintRetryPrompt(t string) {
+	fmt.Printf("Expected %s. Pleasy try again!\n", t)
 }
 
-// MaxBetween returns takes in 2 integers as input and returns the larger value.
-func MaxBetween(x, y int) int {
-	if x > y {
-		return x
+// ReadInput prompts for the value of name and reads lines from reader until one
+// is accepted by valid. Variable names are replaced by their values first. When
+// the session is not interactive, or the input has ended, an invalid value is
+// an error instead of a reason to ask again.
+func ReadInput(reader *bufio.Reader, name, typeName string, valid func(string) bool) (string, error) {
+	for {
+		line, err := ReadLine(reader, name+" = ")
+		CurrentSession.History.Add(line)
+		input := CurrentSession.ExpandInput(strings.TrimSpace(line))
+		if valid(input) {
+			return input, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("input ended before a value for %s was given", name)
+		}
+		if !CurrentSession.Interactive {
+			return "", fmt.Errorf("expected %s for %s, got %q", typeName, name, input)
+		}
+		PrintRetryPrompt(typeName)
 	}
-	return y
-}
-
-// PrintRetryPrompt is used in UI code to prompt user to renter a value for a
-// function input.
-func PrintRetryPrompt(s string, t string) {
-	fmt.Printf("Expected %s. Pleasy try again!\n", t)
-	fmt.Printf("%s = ", s)
 }
 
 // IsFloat checks if the string, x can be represented as a float.
@@ -78,9 +47,10 @@ func IsFloat(x string) bool {
 	return err == nil
 }
 
-// IsInt checks if the string, x can be represented as an int.
+// IsInt checks if the string, x can be represented as an int. Besides
+// decimal, hexadecimal, binary and octal literals such as 0xff are accepted.
 func IsInt(x string) bool {
-	_, err := strconv.Atoi(strings.TrimSpace(x))
+	_, err := ParseIntLiteral(x)
 	return err == nil
 }
 
@@ -95,6 +65,12 @@ func IsFloatArray(data []string) bool {
 	return true
 }
 
+// IsFloatArrayString checks if a comma separated string can be represented as
+// an array of floats.
+func IsFloatArrayString(x string) bool {
+	return IsFloatArray(ParseInputToArray(x))
+}
+
 // ParseStringArrayToFloatArray converts an array of strings to an array of
 // floats.
 func ParseStringArrayToFloatArray(data []string) []float64 {
@@ -106,6 +82,14 @@ func ParseStringArrayToFloatArray(data []string) []float64 {
 	return floatArr
 }
 
+// StripComment removes a trailing # comment from a line of input.
+func StripComment(input string) string {
+	if i := strings.Index(input, "#"); i >= 0 {
+		return input[:i]
+	}
+	return input
+}
+
 // ParseInputToArray turns a string representation of an array into an array of
 // strings. e.g. "1,2,3" -> {"1", "2", "3"}
 func ParseInputToArray(input string) []string {
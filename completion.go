@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+/**
+This file contains the tab completion of the line editor.
+*/
+
+// SessionCommands are the commands of the main prompt that are not functions.
+var SessionCommands = []string{
+	"base", "benchmark", "bigint", "clear", "complex", "deg", "del", "digits", "drop", "dup", "exit", "format", "frac", "funcs",
+	"grad", "help", "history", "inspect", "mc", "mr", "output", "precision", "rad", "roll", "rpn", "source", "swap",
+	"tests", "vars", "word",
+}
+
+// CompletionCandidates returns every name that can be completed at the main
+// prompt: commands, functions and their aliases, constants and the variables
+// and functions defined in the session.
+func CompletionCandidates() []string {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if isWord(name) {
+			seen[name] = true
+		}
+	}
+	for _, name := range SessionCommands {
+		add(name)
+	}
+	for _, name := range FunctionNames() {
+		add(name)
+	}
+	for name := range Constants {
+		add(name)
+	}
+	for name := range CurrentSession.Scope.Variables {
+		add(name)
+	}
+	for name := range CurrentSession.Scope.Functions {
+		add(name)
+	}
+	candidates := make([]string, 0, len(seen))
+	for name := range seen {
+		candidates = append(candidates, name)
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// Complete returns the candidates starting with prefix and the longest prefix
+// they all share.
+func Complete(prefix string, candidates []string) ([]string, string) {
+	matches := make([]string, 0)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) == 0 {
+		return matches, prefix
+	}
+	common := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	return matches, common
+}
+
+func isWord(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !isWordRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
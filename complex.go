@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"calculator/arithmetic"
+	"calculator/trig"
+)
+
+/**
+This file contains complex numbers. A number followed by i is imaginary and i
+is a constant, so 3+4i is a complex number, and + - * / ^ as well as sqrt, ln,
+log, exponent and the trigonometry functions accept and return complex values.
+Real arguments outside the domain of a function are retried in the complex
+plane, e.g.
+
+    >sqrt(-4)
+    sqrt(-4) = 2.00000i
+    >ln(-1)
+    ln(-1) = 3.14159i
+    >complex polar
+    >3+4i
+    3+4i = 5.00000 ∠ 0.92730
+
+Complex numbers are shown in rectangular form, a + bi, or in polar form, the
+modulus and the argument in the angle unit of the session, set with complex
+rect or complex polar.
+*/
+
+// ComplexForm is the way complex results are displayed.
+type ComplexForm string
+
+const (
+	// RectangularForm shows complex numbers as a + bi.
+	RectangularForm ComplexForm = "rect"
+	// PolarForm shows complex numbers as their modulus and argument.
+	PolarForm ComplexForm = "polar"
+)
+
+// ImaginaryUnit is the suffix of imaginary literals and the name of the
+// constant i.
+const ImaginaryUnit = "i"
+
+// ComplexValue wraps a complex number in a Value. Numbers without an imaginary
+// part are returned as floats.
+func ComplexValue(z complex128) Value {
+	if imag(z) == 0 {
+		return FloatValue(real(z))
+	}
+	return Value{Kind: ComplexKind, Complex: z}
+}
+
+// AsComplex returns the numeric value of v as a complex number.
+func (v Value) AsComplex() complex128 {
+	if v.Kind == ComplexKind {
+		return v.Complex
+	}
+	return complex(v.AsFloat(), 0)
+}
+
+// IsComplex reports whether s is a complex number such as 3+4i.
+func IsComplex(s string) bool {
+	_, err := strconv.ParseComplex(strings.TrimSpace(s), 128)
+	return err == nil
+}
+
+// FormatComplex prints z in the complex form of the session.
+func FormatComplex(z complex128) string {
+	if CurrentSession.Complex == PolarForm {
+		angle := FromRadians(arithmetic.Argument(z), CurrentSession.Angle)
+		unit := ""
+		if CurrentSession.Angle != Radians {
+			unit = string(CurrentSession.Angle)
+		}
+		return fmt.Sprintf("%s ∠ %s%s", FormatNumber(arithmetic.Modulus(z)), FormatNumber(angle), unit)
+	}
+	imaginary := FormatNumber(arithmetic.AbsFloat(imag(z))) + ImaginaryUnit
+	switch {
+	case real(z) == 0 && imag(z) < 0:
+		return "-" + imaginary
+	case real(z) == 0:
+		return imaginary
+	case imag(z) < 0:
+		return FormatNumber(real(z)) + " - " + imaginary
+	}
+	return FormatNumber(real(z)) + " + " + imaginary
+}
+
+// applyComplexOperator computes x op y on complex numbers.
+func applyComplexOperator(op string, x, y complex128) (Value, error) {
+	switch op {
+	case "+":
+		return ComplexValue(x + y), nil
+	case "-":
+		return ComplexValue(x - y), nil
+	case "*":
+		return ComplexValue(x * y), nil
+	case "/":
+		if y == 0 {
+			return Value{}, ErrDivisionByZero
+		}
+		return ComplexValue(x / y), nil
+	case "^":
+		return complexResult(arithmetic.ComplexPower(x, y))
+	}
+	return Value{}, fmt.Errorf("unknown operator %s", op)
+}
+
+// complexResult wraps the result of a library function in a Value.
+func complexResult(z complex128, err error) (Value, error) {
+	if err != nil {
+		return Value{}, err
+	}
+	return ComplexValue(z), nil
+}
+
+// complexSeries is a series evaluated with complex arithmetic, taking z and
+// the number of terms n.
+type complexSeries func(z complex128, n int) (complex128, error)
+
+// exactComplex adapts a complex series that is defined for every z.
+func exactComplex(f func(z complex128, n int) complex128) complexSeries {
+	return func(z complex128, n int) (complex128, error) {
+		return f(z, n), nil
+	}
+}
+
+// complexFunction evaluates c for complex arguments and for real arguments
+// outside the domain of the real implementation f, e.g. ln(-1).
+func complexFunction(c complexSeries, f func(args []Value) (Value, error)) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		if args[0].Kind != ComplexKind {
+			v, err := f(args)
+			if !isDomainError(err) {
+				return v, err
+			}
+		}
+		return complexResult(c(args[0].AsComplex(), args[1].Int))
+	}
+}
+
+// isDomainError reports whether err rejects a real argument that has a
+// complex result.
+func isDomainError(err error) bool {
+	return errors.Is(err, arithmetic.ErrSqrtDomain) || errors.Is(err, arithmetic.ErrLogDomain) || errors.Is(err, trig.ErrDomain)
+}
+
+// complexAngle converts the angle z from one unit to another. The conversion
+// is a multiplication, so both parts are scaled, the same for arguments,
+// results and conversions, and sin(arcsin(z)) gives back z in every unit.
+func complexAngle(z complex128, from, to AngleUnit) complex128 {
+	return complex(ConvertAngle(real(z), from, to), ConvertAngle(imag(z), from, to))
+}
+
+// ExecuteComplexCommand shows the complex form of the session, or changes it
+// when rect or polar is given.
+func ExecuteComplexCommand(args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: complex rect|polar")
+	}
+	if len(args) == 1 {
+		switch form := ComplexForm(strings.ToLower(args[0])); form {
+		case RectangularForm, PolarForm:
+			CurrentSession.Complex = form
+		default:
+			return fmt.Errorf("unknown complex form %s, expected rect or polar", args[0])
+		}
+	}
+	fmt.Printf("Complex form: %s, e.g. 3+4i = %s\n", CurrentSession.Complex, FormatComplex(3+4i))
+	return nil
+}
@@ -0,0 +1,674 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"calculator/arithmetic"
+)
+
+/**
+This file contains the infix expression evaluator used by the main prompt. An
+expression is first split into tokens, then parsed into a tree of Nodes by a
+recursive descent parser and finally evaluated. A statement is either an
+expression, an assignment of an expression to a variable, e.g. x = sin(0.5),
+or the definition of a function, e.g. f(x, y) = sqrt(x^2 + y^2). An expression
+may end with a conversion to an angle unit, e.g. arcsin(0.5) -> deg.
+
+Precedence from lowest to highest:
+    |          bitwise or
+    &          bitwise and
+    << >>      shifts
+    + -        addition and subtraction
+    * /        multiplication and division
+    - ~        unary minus and bitwise not
+    ^          power (right associative)
+    !          factorial
+    deg rad    angle unit suffix, e.g. 30deg
+    i          imaginary suffix, e.g. 4i
+*/
+
+// TokenKind identifies the type of a Token.
+type TokenKind int
+
+const (
+	// NumberToken is a numeric literal such as 3 or 0.25.
+	NumberToken TokenKind = iota
+	// IdentifierToken is the name of a function, variable or constant.
+	IdentifierToken
+	// OperatorToken is an operator or punctuation character.
+	OperatorToken
+	// EndToken marks the end of the input.
+	EndToken
+)
+
+// Token is a single lexical element of an expression.
+type Token struct {
+	Kind     TokenKind
+	Text     string
+	Position int
+}
+
+// Tokenize splits an expression into tokens.
+func Tokenize(input string) ([]Token, error) {
+	tokens := make([]Token, 0)
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case isRadixPrefix(runes, i):
+			start := i
+			i = scanRadixNumber(runes, i)
+			tokens = append(tokens, Token{NumberToken, string(runes[start:i]), start})
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i = scanNumber(runes, i)
+			tokens = append(tokens, Token{NumberToken, string(runes[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, Token{IdentifierToken, string(runes[start:i]), start})
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '>':
+			tokens = append(tokens, Token{OperatorToken, "->", i})
+			i += 2
+		case i+1 < len(runes) && (string(runes[i:i+2]) == "<<" || string(runes[i:i+2]) == ">>"):
+			tokens = append(tokens, Token{OperatorToken, string(runes[i : i+2]), i})
+			i += 2
+		case strings.ContainsRune("+-*/^!(),[]=&|~", r):
+			tokens = append(tokens, Token{OperatorToken, string(r), i})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", r, i+1)
+		}
+	}
+	return append(tokens, Token{EndToken, "", len(runes)}), nil
+}
+
+// IsIdentifier reports whether name is read as a single identifier, a letter
+// or underscore followed by letters, digits and underscores.
+func IsIdentifier(name string) bool {
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// scanNumber returns the index just past the numeric literal starting at i.
+// An exponent is only consumed when it is followed by digits, so 2e3 is a
+// number while 2e ends the number before the name e.
+func scanNumber(runes []rune, i int) int {
+	for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+		i++
+	}
+	if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+		j := i + 1
+		if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+			j++
+		}
+		if j < len(runes) && unicode.IsDigit(runes[j]) {
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			return j
+		}
+	}
+	return i
+}
+
+// Scope holds the variables, user-defined functions and memory registers
+// visible while an expression is evaluated. Calling a user-defined function
+// creates a child scope holding its arguments, names not found there are
+// looked up in the parent.
+type Scope struct {
+	Variables map[string]Value
+	Functions map[string]*UserFunction
+	Memory    map[string]Value
+	parent    *Scope
+	depth     int
+}
+
+// NewScope returns an empty top level scope.
+func NewScope() *Scope {
+	return &Scope{
+		Variables: make(map[string]Value),
+		Functions: make(map[string]*UserFunction),
+		Memory:    make(map[string]Value),
+	}
+}
+
+// Lookup finds the value of a variable in this scope or any parent scope.
+func (s *Scope) Lookup(name string) (Value, bool) {
+	for scope := s; scope != nil; scope = scope.parent {
+		if v, ok := scope.Variables[name]; ok {
+			return v, true
+		}
+	}
+	return Value{}, false
+}
+
+// child returns a scope for the body of a function call that shares the
+// functions of s.
+func (s *Scope) child(variables map[string]Value) *Scope {
+	return &Scope{
+		Variables: variables,
+		Functions: s.Functions,
+		Memory:    s.Memory,
+		parent:    s,
+		depth:     s.depth + 1,
+	}
+}
+
+// Node is an element of a parsed expression tree.
+type Node interface {
+	Eval(scope *Scope) (Value, error)
+}
+
+// NumberNode is a numeric literal.
+type NumberNode struct {
+	Value Value
+}
+
+// Eval returns the literal value.
+func (n NumberNode) Eval(scope *Scope) (Value, error) {
+	return n.Value, nil
+}
+
+// IdentifierNode refers to a variable or a constant.
+type IdentifierNode struct {
+	Name string
+}
+
+// Eval looks the identifier up in the scope and then in the constants, which
+// are computed to the digits of the session when it is set.
+func (n IdentifierNode) Eval(scope *Scope) (Value, error) {
+	if v, ok := scope.Lookup(n.Name); ok {
+		return v, nil
+	}
+	if v, ok := preciseConstant(n.Name); ok {
+		return v, nil
+	}
+	if v, ok := Constants[n.Name]; ok {
+		return v, nil
+	}
+	return Value{}, fmt.Errorf("unknown variable %s", n.Name)
+}
+
+// UnaryNode is a prefix or postfix operator applied to a single operand.
+type UnaryNode struct {
+	Operator string
+	Operand  Node
+}
+
+// Eval applies the operator to the evaluated operand.
+func (n UnaryNode) Eval(scope *Scope) (Value, error) {
+	x, err := n.Operand.Eval(scope)
+	if err != nil {
+		return Value{}, err
+	}
+	switch n.Operator {
+	case "!":
+		return FactorialValue(x)
+	case "~":
+		return BitwiseNotValue(x)
+	}
+	return Negate(x)
+}
+
+// BinaryNode is an infix operator applied to two operands.
+type BinaryNode struct {
+	Operator string
+	Left     Node
+	Right    Node
+}
+
+// Eval evaluates both operands and applies the operator.
+func (n BinaryNode) Eval(scope *Scope) (Value, error) {
+	x, err := n.Left.Eval(scope)
+	if err != nil {
+		return Value{}, err
+	}
+	y, err := n.Right.Eval(scope)
+	if err != nil {
+		return Value{}, err
+	}
+	return ApplyBinaryOperator(n.Operator, x, y)
+}
+
+// CallNode is a function call.
+type CallNode struct {
+	Name string
+	Args []Node
+}
+
+// Eval evaluates the arguments and calls either the built-in or the
+// user-defined function of that name.
+func (n CallNode) Eval(scope *Scope) (Value, error) {
+	args := make([]Value, len(n.Args))
+	for i, arg := range n.Args {
+		v, err := arg.Eval(scope)
+		if err != nil {
+			return Value{}, err
+		}
+		args[i] = v
+	}
+	if f, ok := scope.Functions[n.Name]; ok {
+		return f.Call(args, scope)
+	}
+	return CallBuiltin(n.Name, args)
+}
+
+// ListNode is a data set literal such as [1, 2, 3].
+type ListNode struct {
+	Elements []Node
+}
+
+// Eval evaluates every element into a single data set.
+func (n ListNode) Eval(scope *Scope) (Value, error) {
+	data := make([]float64, 0, len(n.Elements))
+	for _, element := range n.Elements {
+		v, err := element.Eval(scope)
+		if err != nil {
+			return Value{}, err
+		}
+		if v.Kind == ComplexKind {
+			return Value{}, errors.New("data sets must not hold complex numbers")
+		}
+		data = append(data, v.AsList()...)
+	}
+	return ListValue(data), nil
+}
+
+// MemoryNode recalls the value of a memory register.
+type MemoryNode struct {
+	Slot string
+}
+
+// Eval returns the value held in the register, an empty register holds 0.
+func (n MemoryNode) Eval(scope *Scope) (Value, error) {
+	return scope.Memory[n.Slot], nil
+}
+
+// AssignmentNode stores the value of an expression in a variable.
+type AssignmentNode struct {
+	Name  string
+	Value Node
+}
+
+// Eval evaluates the expression, stores it in the scope and returns it.
+func (n AssignmentNode) Eval(scope *Scope) (Value, error) {
+	v, err := n.Value.Eval(scope)
+	if err != nil {
+		return Value{}, err
+	}
+	scope.Variables[n.Name] = v
+	return v, nil
+}
+
+// Parser is a recursive descent parser over a list of tokens.
+type Parser struct {
+	tokens []Token
+	pos    int
+}
+
+// ParseExpression parses input into an expression tree.
+func ParseExpression(input string) (Node, error) {
+	tokens, err := Tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &Parser{tokens: tokens}
+	node, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectEnd(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// ParseStatement parses input into a function definition, an assignment or an
+// expression.
+func ParseStatement(input string) (Node, error) {
+	tokens, err := Tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	if params, bodyStart, ok := matchFunctionHeader(tokens); ok {
+		return parseFunctionDefinition(input, tokens, params, bodyStart)
+	}
+	p := &Parser{tokens: tokens}
+	name := ""
+	if len(tokens) > 2 && tokens[0].Kind == IdentifierToken && tokens[1].Text == "=" {
+		name = tokens[0].Text
+		if _, ok := Constants[name]; ok {
+			return nil, fmt.Errorf("cannot assign to constant %s", name)
+		}
+		p.pos = 2
+	}
+	node, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("->") {
+		t := p.next()
+		switch {
+		case t.Kind == IdentifierToken && t.Text == DecimalConversion:
+			node = DecimalConversionNode{node}
+		case t.Kind == IdentifierToken && IsAngleUnit(t.Text):
+			node = AngleConversionNode{node, "", AngleUnit(t.Text)}
+		default:
+			return nil, fmt.Errorf("expected deg, rad, grad or dec after -> at position %d", t.Position+1)
+		}
+	}
+	if err := p.expectEnd(); err != nil {
+		return nil, err
+	}
+	if name != "" {
+		return AssignmentNode{name, node}, nil
+	}
+	return node, nil
+}
+
+// EvaluateExpression parses and evaluates a statement within scope.
+func EvaluateExpression(input string, scope *Scope) (Value, error) {
+	node, err := ParseStatement(input)
+	if err != nil {
+		return Value{}, err
+	}
+	return node.Eval(scope)
+}
+
+func (p *Parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *Parser) next() Token {
+	t := p.tokens[p.pos]
+	if t.Kind != EndToken {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator op.
+func (p *Parser) accept(op string) bool {
+	if t := p.peek(); t.Kind == OperatorToken && t.Text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *Parser) expect(op string) error {
+	if !p.accept(op) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *Parser) expectEnd() error {
+	if p.peek().Kind != EndToken {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *Parser) unexpected() error {
+	t := p.peek()
+	if t.Kind == EndToken {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at position %d", t.Text, t.Position+1)
+}
+
+// parseExpression handles |.
+func (p *Parser) parseExpression() (Node, error) {
+	return p.parseBinary(p.parseBitwiseAnd, "|")
+}
+
+// parseBitwiseAnd handles &.
+func (p *Parser) parseBitwiseAnd() (Node, error) {
+	return p.parseBinary(p.parseShift, "&")
+}
+
+// parseShift handles << and >>.
+func (p *Parser) parseShift() (Node, error) {
+	return p.parseBinary(p.parseSum, "<<", ">>")
+}
+
+// parseBinary parses a left associative chain of the operators ops between
+// operands parsed by operand.
+func (p *Parser) parseBinary(operand func() (Node, error), ops ...string) (Node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.Kind != OperatorToken || !containsString(ops, t.Text) {
+			return left, nil
+		}
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryNode{t.Text, left, right}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSum handles + and -.
+func (p *Parser) parseSum() (Node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().Text
+		if p.peek().Kind != OperatorToken || (op != "+" && op != "-") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryNode{op, left, right}
+	}
+}
+
+// parseTerm handles * and /.
+func (p *Parser) parseTerm() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().Text
+		if p.peek().Kind != OperatorToken || (op != "*" && op != "/") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryNode{op, left, right}
+	}
+}
+
+// parseUnary handles a leading +, - or ~.
+func (p *Parser) parseUnary() (Node, error) {
+	for _, op := range []string{"-", "~"} {
+		if p.accept(op) {
+			operand, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return UnaryNode{op, operand}, nil
+		}
+	}
+	if p.accept("+") {
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+// parsePower handles ^, which binds tighter than unary minus on its left so
+// that -2^2 = -4, and is right associative so that 2^3^2 = 2^9.
+func (p *Parser) parsePower() (Node, error) {
+	base, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("^") {
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return BinaryNode{"^", base, exponent}, nil
+	}
+	return base, nil
+}
+
+// parsePostfix handles the factorial operator and angle unit suffixes.
+func (p *Parser) parsePostfix() (Node, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("!") {
+		node = UnaryNode{"!", node}
+	}
+	if t := p.peek(); t.Kind == IdentifierToken && IsAngleUnit(t.Text) {
+		p.next()
+		node = AngleConversionNode{node, AngleUnit(t.Text), ""}
+	}
+	return node, nil
+}
+
+// parsePrimary handles literals, including imaginary ones such as 4i, names,
+// function calls, parentheses and data sets.
+func (p *Parser) parsePrimary() (Node, error) {
+	t := p.peek()
+	switch {
+	case t.Kind == NumberToken:
+		p.next()
+		if u := p.peek(); u.Kind == IdentifierToken && u.Text == ImaginaryUnit && u.Position == t.Position+len([]rune(t.Text)) {
+			p.next()
+			return parseImaginaryLiteral(t)
+		}
+		return parseNumberLiteral(t)
+	case t.Kind == IdentifierToken && t.Text == MemoryRecallName:
+		p.next()
+		return p.parseMemoryRecall()
+	case t.Kind == IdentifierToken:
+		p.next()
+		if p.accept("(") {
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			return CallNode{t.Text, args}, nil
+		}
+		return IdentifierNode{t.Text}, nil
+	case p.accept("("):
+		node, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	case p.accept("["):
+		elements, err := p.parseList("]")
+		if err != nil {
+			return nil, err
+		}
+		return ListNode{elements}, nil
+	}
+	return nil, p.unexpected()
+}
+
+// parseMemoryRecall parses mr, which recalls the default memory register, or
+// mr(name), which recalls a named one.
+func (p *Parser) parseMemoryRecall() (Node, error) {
+	if !p.accept("(") {
+		return MemoryNode{DefaultMemorySlot}, nil
+	}
+	t := p.peek()
+	if t.Kind != IdentifierToken {
+		return nil, p.unexpected()
+	}
+	p.next()
+	return MemoryNode{t.Text}, p.expect(")")
+}
+
+// parseList parses comma separated expressions up to the closing token.
+func (p *Parser) parseList(closing string) ([]Node, error) {
+	nodes := make([]Node, 0)
+	if p.accept(closing) {
+		return nodes, nil
+	}
+	for {
+		node, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if p.accept(closing) {
+			return nodes, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseNumberLiteral turns a number token into an int if it has no fraction
+// or exponent and fits in an int, otherwise into a float. In the big integer
+// mode integers of any length are kept exact, and while digits is set other
+// numbers are read in arbitrary precision.
+func parseNumberLiteral(t Token) (Node, error) {
+	if x, err := ParseIntLiteral(t.Text); err == nil {
+		return NumberNode{IntValue(x)}, nil
+	}
+	if x, err := arithmetic.ParseBigInt(t.Text); err == nil && BigIntsEnabled() {
+		return NumberNode{BigValue(x)}, nil
+	}
+	if v, ok := parsePrecise(t.Text); ok {
+		return NumberNode{v}, nil
+	}
+	x, err := strconv.ParseFloat(t.Text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %s at position %d", t.Text, t.Position+1)
+	}
+	return NumberNode{FloatValue(x)}, nil
+}
+
+// parseImaginaryLiteral turns a number token followed by i into an imaginary
+// number, e.g. 4i.
+func parseImaginaryLiteral(t Token) (Node, error) {
+	x, err := strconv.ParseFloat(t.Text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %si at position %d", t.Text, t.Position+1)
+	}
+	return NumberNode{ComplexValue(complex(0, x))}, nil
+}
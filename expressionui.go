@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+)
+
+// EvaluateAndPrintExpression evaluates an infix expression, assignment or
+// function definition typed at the main prompt and prints its value. It
+// returns the reason the expression could not be computed, if any.
+func EvaluateAndPrintExpression(input string) error {
+	node, err := ParseStatement(input)
+	if err != nil {
+		return err
+	}
+	v, err := node.Eval(CurrentSession.Scope)
+	if err != nil {
+		return err
+	}
+	if definition, ok := node.(FunctionDefinition); ok {
+		fmt.Printf("Defined %s\n", definition.Function)
+		return nil
+	}
+	CurrentSession.SetAnswer(v)
+	PrintExpressionResult(input, v)
+	return nil
+}
+
+// PrintExpressionResult pretty prints the value of an expression.
+func PrintExpressionResult(input string, v Value) {
+	if JSONOutputEnabled() {
+		r := ResultRecord{Expression: input}
+		r.SetResult(v)
+		PrintResultRecord(r)
+		return
+	}
+	fmt.Printf("%s = %s\n", input, v)
+	PrintWordStatus(v)
+	PrintMemoryStatus()
+	fmt.Println("===============================================================")
+}
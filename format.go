@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/**
+This file contains the number format used to display floating point results,
+set with the format command, e.g. format sci 3.
+
+    fix N      N digits after the decimal point, e.g. 3.14159
+    sig N      N significant figures, e.g. 0.0000123457
+    sci N      scientific notation with N decimals, e.g. 1.23457e-05
+    eng N      engineering notation, the exponent is a multiple of 3
+    auto N     the shorter of fixed and scientific with up to N significant
+               figures and no trailing zeros
+
+Integer results are always printed exactly.
+*/
+
+// FormatStyle is one of the ways a number can be displayed.
+type FormatStyle string
+
+const (
+	// FixedFormat prints a fixed number of decimals.
+	FixedFormat FormatStyle = "fix"
+	// SignificantFormat prints a number of significant figures.
+	SignificantFormat FormatStyle = "sig"
+	// ScientificFormat prints a mantissa and a power of ten.
+	ScientificFormat FormatStyle = "sci"
+	// EngineeringFormat prints a mantissa and a power of ten that is a
+	// multiple of 3.
+	EngineeringFormat FormatStyle = "eng"
+	// AutoFormat picks fixed or scientific notation depending on the size of
+	// the number.
+	AutoFormat FormatStyle = "auto"
+)
+
+// MaxFormatDigits is the largest number of digits a format can show.
+const MaxFormatDigits = 17
+
+// defaultFormatDigits is the number of digits used when a style is selected
+// without giving one.
+var defaultFormatDigits = map[FormatStyle]int{
+	FixedFormat:       5,
+	SignificantFormat: 6,
+	ScientificFormat:  5,
+	EngineeringFormat: 5,
+	AutoFormat:        10,
+}
+
+// NumberFormat is a style together with its number of digits.
+type NumberFormat struct {
+	Style  FormatStyle
+	Digits int
+}
+
+// DefaultNumberFormat is the format results have always been printed in.
+var DefaultNumberFormat = NumberFormat{FixedFormat, 5}
+
+// ParseNumberFormat reads a format such as "sci 3" or "auto" from its fields.
+func ParseNumberFormat(fields []string) (NumberFormat, error) {
+	if len(fields) == 0 || len(fields) > 2 {
+		return NumberFormat{}, errors.New("usage: format fix|sig|sci|eng|auto [digits]")
+	}
+	style := FormatStyle(strings.ToLower(fields[0]))
+	digits, ok := defaultFormatDigits[style]
+	if !ok {
+		return NumberFormat{}, fmt.Errorf("unknown format %s, expected fix, sig, sci, eng or auto", fields[0])
+	}
+	if len(fields) == 2 {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return NumberFormat{}, fmt.Errorf("invalid number of digits %s", fields[1])
+		}
+		digits = n
+	}
+	minimum := 0
+	if style == SignificantFormat || style == AutoFormat {
+		minimum = 1
+	}
+	if digits < minimum || digits > MaxFormatDigits {
+		return NumberFormat{}, fmt.Errorf("number of digits for %s must be between %d and %d", style, minimum, MaxFormatDigits)
+	}
+	return NumberFormat{style, digits}, nil
+}
+
+// String returns the format as it is typed, e.g. sci 3.
+func (f NumberFormat) String() string {
+	return fmt.Sprintf("%s %d", f.Style, f.Digits)
+}
+
+// Format formats x according to f.
+func (f NumberFormat) Format(x float64) string {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	switch f.Style {
+	case SignificantFormat:
+		return formatSignificant(x, f.Digits)
+	case ScientificFormat:
+		return strconv.FormatFloat(x, 'e', f.Digits, 64)
+	case EngineeringFormat:
+		return formatEngineering(x, f.Digits)
+	case AutoFormat:
+		return strconv.FormatFloat(x, 'g', f.Digits, 64)
+	default:
+		return strconv.FormatFloat(x, 'f', f.Digits, 64)
+	}
+}
+
+// formatSignificant rounds x to n significant figures and prints it without
+// an exponent unless it is very large or very small.
+func formatSignificant(x float64, n int) string {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(x, 'e', n-1, 64), 64)
+	if rounded == 0 {
+		return strconv.FormatFloat(0, 'f', n-1, 64)
+	}
+	exponent := int(math.Floor(math.Log10(math.Abs(rounded))))
+	if exponent < -6 || exponent >= 21 {
+		return strconv.FormatFloat(x, 'e', n-1, 64)
+	}
+	decimals := n - 1 - exponent
+	if decimals < 0 {
+		decimals = 0
+	}
+	return strconv.FormatFloat(rounded, 'f', decimals, 64)
+}
+
+// formatEngineering prints x with n decimals and an exponent that is a
+// multiple of 3, so that the mantissa is between 1 and 1000.
+func formatEngineering(x float64, n int) string {
+	exponent := 0
+	if x != 0 {
+		exponent = int(math.Floor(math.Log10(math.Abs(x))/3)) * 3
+	}
+	mantissa := strconv.FormatFloat(x/math.Pow(10, float64(exponent)), 'f', n, 64)
+	// Rounding may carry the mantissa up to 1000, e.g. 999.9996 with n = 3.
+	if m, _ := strconv.ParseFloat(mantissa, 64); math.Abs(m) >= 1000 {
+		exponent += 3
+		mantissa = strconv.FormatFloat(x/math.Pow(10, float64(exponent)), 'f', n, 64)
+	}
+	return fmt.Sprintf("%se%+03d", mantissa, exponent)
+}
+
+// FormatNumber formats x in the number format of the session.
+func FormatNumber(x float64) string {
+	return CurrentSession.Format.Format(x)
+}
+
+// ExecuteFormatCommand shows the number format of the session, or changes it
+// when a style is given.
+func ExecuteFormatCommand(args []string) error {
+	if len(args) > 0 {
+		f, err := ParseNumberFormat(args)
+		if err != nil {
+			return err
+		}
+		CurrentSession.Format = f
+	}
+	fmt.Printf("Number format: %s, e.g. %s\n", CurrentSession.Format, FormatNumber(math.Pi*1000))
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"calculator/arithmetic"
+)
+
+/**
+This file contains the fraction mode, set with frac on, frac mixed or frac
+off. In this mode dividing integers no longer truncates the quotient but gives
+an exact reduced fraction, and + - * ^ keep fractions exact, e.g.
+
+    >frac on
+    >89/24
+    89/24 = 89/24
+    >1/3 + 1/6
+    1/3 + 1/6 = 1/2
+    >frac mixed
+    >89/24
+    89/24 = 3 17/24
+    >89/24 -> dec
+    89/24 -> dec = 3.70833
+
+Fractions are exact however long their numerator and denominator grow, e.g.
+(2/3)^100, and with bigint on the quotients of big integers are fractions too.
+Anything involving a float is still computed in floating point, and a word
+size takes precedence over the fraction mode.
+*/
+
+// FractionMode is how the quotients of integers are kept.
+type FractionMode string
+
+const (
+	// FractionsOff truncates quotients of integers.
+	FractionsOff FractionMode = "off"
+	// ImproperFractions keeps quotients as fractions such as 7/3.
+	ImproperFractions FractionMode = "on"
+	// MixedFractions keeps quotients as fractions shown as mixed numbers
+	// such as 2 1/3.
+	MixedFractions FractionMode = "mixed"
+)
+
+// DecimalConversion is the name after -> that converts a fraction to a
+// decimal, e.g. 7/3 -> dec.
+const DecimalConversion = "dec"
+
+// FractionsEnabled reports whether quotients of integers are kept as
+// fractions. Word sizes take precedence.
+func FractionsEnabled() bool {
+	mode := CurrentSession.Fractions
+	return (mode == ImproperFractions || mode == MixedFractions) && !CurrentSession.Word.Enabled()
+}
+
+// FractionValue wraps a fraction in a Value. Whole numbers are returned as
+// ints, or like other integers too large for an int as big integers in the
+// big integer mode and as floats otherwise.
+func FractionValue(f arithmetic.Fraction) Value {
+	if !f.IsInteger() {
+		return Value{Kind: FracKind, Frac: f}
+	}
+	if n, ok := f.Numerator().Int(); ok {
+		return IntValue(n)
+	}
+	if BigIntsEnabled() {
+		return BigValue(f.Numerator())
+	}
+	return FloatValue(f.Numerator().Float())
+}
+
+// AsFraction returns the value of v as a fraction if v holds an integer, a big
+// integer or a fraction.
+func (v Value) AsFraction() (arithmetic.Fraction, bool) {
+	switch v.Kind {
+	case IntKind:
+		f, err := arithmetic.NewFraction(v.Int, 1)
+		return f, err == nil
+	case BigKind:
+		f, err := arithmetic.NewBigFraction(v.Big, arithmetic.NewBigInt(1))
+		return f, err == nil
+	case FracKind:
+		return v.Frac, true
+	}
+	return arithmetic.Fraction{}, false
+}
+
+// FormatFraction prints f in the fraction mode of the session.
+func FormatFraction(f arithmetic.Fraction) string {
+	if CurrentSession.Fractions == MixedFractions {
+		return f.MixedString()
+	}
+	return f.String()
+}
+
+// applyFractionOperator computes x op y on fractions. Powers with an exponent
+// that is not whole are computed in floating point and exponents are bounded
+// like those of big integers.
+func applyFractionOperator(op string, x, y arithmetic.Fraction) (Value, error) {
+	var f arithmetic.Fraction
+	var err error
+	switch op {
+	case "+":
+		f, err = arithmetic.FractionAdd(x, y)
+	case "-":
+		f, err = arithmetic.FractionSubtract(x, y)
+	case "*":
+		f, err = arithmetic.FractionMultiply(x, y)
+	case "/":
+		f, err = arithmetic.FractionDivide(x, y)
+	case "^":
+		if !y.IsInteger() {
+			return applyFloatOperator(op, x.Float(), y.Float())
+		}
+		power, ok := y.Numerator().Int()
+		if !ok || arithmetic.Abs(power) > MaxExponent {
+			return Value{}, fmt.Errorf("power is too large, y must be at most %d", MaxExponent)
+		}
+		for _, part := range []arithmetic.BigInt{x.Numerator(), x.Denominator()} {
+			if err := checkPowerDigits(part, arithmetic.Abs(power)); err != nil {
+				return Value{}, err
+			}
+		}
+		f, err = arithmetic.FractionPower(x, power)
+	default:
+		return Value{}, fmt.Errorf("unknown operator %s", op)
+	}
+	if err != nil {
+		return Value{}, err
+	}
+	return FractionValue(f), nil
+}
+
+// DecimalConversionNode converts the value of Expr to a decimal, e.g.
+// 7/3 -> dec.
+type DecimalConversionNode struct {
+	Expr Node
+}
+
+// Eval evaluates the expression and converts a fraction to a float.
+func (n DecimalConversionNode) Eval(scope *Scope) (Value, error) {
+	v, err := n.Expr.Eval(scope)
+	if err != nil {
+		return Value{}, err
+	}
+	if !v.IsNumber() {
+		return Value{}, errors.New("only numbers can be converted to decimals")
+	}
+	if v.Kind == FracKind {
+		return FloatValue(v.Frac.Float()), nil
+	}
+	return v, nil
+}
+
+// ExecuteFractionCommand shows the fraction mode of the session, or changes it
+// when on, mixed or off is given.
+func ExecuteFractionCommand(args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: frac on|mixed|off")
+	}
+	if len(args) == 1 {
+		if err := setFractionMode(args[0]); err != nil {
+			return err
+		}
+	}
+	if !FractionsEnabled() {
+		fmt.Println("Fractions: off.")
+		return nil
+	}
+	example, _ := arithmetic.NewFraction(89, 24)
+	fmt.Printf("Fractions: %s, e.g. 89/24 = %s\n", CurrentSession.Fractions, FormatFraction(example))
+	return nil
+}
+
+// setFractionMode sets the fraction mode of the session from its name.
+func setFractionMode(name string) error {
+	switch mode := FractionMode(strings.ToLower(name)); mode {
+	case FractionsOff, ImproperFractions, MixedFractions:
+		CurrentSession.Fractions = mode
+		return nil
+	}
+	return fmt.Errorf("unknown fraction mode %s, expected on, mixed or off", name)
+}
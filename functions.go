@@ -0,0 +1,377 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"calculator/arithmetic"
+	"calculator/stats"
+	"calculator/trig"
+)
+
+/**
+This file contains FunctionRegistry, the functions of the calculator, and the
+adapters mapping them to the implementations in the arithmetic, trig and stats
+packages.
+*/
+
+// DefaultTerms is the number of Taylor Series terms used when n is not given.
+const DefaultTerms = 15
+
+// MaxTerms is the largest number of Taylor Series terms that can be asked for.
+// The series are computed term by term, some in time growing with the square
+// of n.
+const MaxTerms = 1000
+
+// MaxPiTerms is the largest number of terms of the series of pi, which gains
+// a digit for every tenfold increase of n.
+const MaxPiTerms = 10000000
+
+// DefaultMarginOfError is the margin of error used by sqrt when it is not
+// given. It applies to the square of the result, and is small enough that
+// every digit shown is correct.
+const DefaultMarginOfError = 1e-12
+
+// Parameters shared by several functions.
+var (
+	termsParam = Param{
+		Name: "n", Kind: IntParam, Optional: true, Default: IntValue(DefaultTerms),
+		Flags:       []string{"terms", "n"},
+		Description: "the number of terms to expand in the Taylor Series. A lower value for n yields a better performance, and vice-versa.",
+		Validate:    inRange("n", 0, MaxTerms),
+	}
+	marginParam = Param{
+		Name: "margin", Kind: FloatParam, Optional: true, Default: FloatValue(DefaultMarginOfError),
+		Flags:       []string{"margin", "m"},
+		Description: "the margin of error within which to compute the result.",
+	}
+	dataParam = Param{
+		Name: "data", Kind: DataParam,
+		Description: "the set of values to analyze, e.g. 1,2,3,4,5. All values must be floats and comma separated.",
+	}
+)
+
+// FunctionRegistry holds every function of the calculator, in the order they
+// are listed by help.
+var FunctionRegistry = []*Function{
+	{Name: "add", Aliases: []string{"+"}, Category: ArithmeticCategory, Description: "adds x and y.",
+		Params: numberParams(NumberParam, "x", "y"), Call: intFunction(arithmetic.BitwiseAdd, "+")},
+	{Name: "subtract", Aliases: []string{"-"}, Category: ArithmeticCategory, Description: "subtracts y from x.",
+		Params: numberParams(NumberParam, "x", "y"), Call: intFunction(arithmetic.BitwiseSubtractFast, "-")},
+	{Name: "multiply", Aliases: []string{"*"}, Category: ArithmeticCategory, Description: "multiplies x by y.",
+		Params: numberParams(NumberParam, "x", "y"), Call: intFunction(arithmetic.KaratsubaMultiplicationFast, "*")},
+	{Name: "divide", Aliases: []string{"/"}, Category: ArithmeticCategory, Description: "divides x by y.",
+		Params: numberParams(NumberParam, "x", "y"), Call: operatorFunction("/")},
+	{Name: "pow", Category: ArithmeticCategory, Description: "raises x to the power of y.",
+		Params: powerParams(), Call: operatorFunction("^")},
+	{Name: "sqrt", Category: ArithmeticCategory, Description: "computes the square root of x.",
+		Params: []Param{{Name: "x", Kind: FloatParam, Description: "the value to compute."}, marginParam},
+		Call:   callSqrt},
+	{Name: "abs", Category: ArithmeticCategory, Description: "computes the absolute value of x.",
+		Params: numberParams(NumberParam, "x"), Call: callAbs},
+	{Name: "conj", Aliases: []string{"conjugate"}, Category: ArithmeticCategory,
+		Description: "computes the complex conjugate of z, a - bi for a + bi.",
+		Params:      complexParams(), Call: callConjugate},
+	{Name: "modulus", Category: ArithmeticCategory, Description: "computes the distance of z from 0.",
+		Params: complexParams(), Call: callModulus},
+	{Name: "arg", Aliases: []string{"argument"}, Category: ArithmeticCategory,
+		Description: "computes the angle of z from the positive real axis.",
+		Params:      complexParams(), Call: angleOutput(callArgument)},
+	{Name: "factorial", Aliases: []string{"!"}, Category: ArithmeticCategory, Description: "computes x!.",
+		Params: numberParams(IntParam, "x"), Call: callFactorial},
+	{Name: "permutation", Aliases: []string{"p"}, Category: ArithmeticCategory,
+		Description: "counts the ordered selections of r items out of n.",
+		Params:      numberParams(IntParam, "n", "r"), Call: nkFunction(arithmetic.Permutation)},
+	{Name: "combination", Aliases: []string{"c"}, Category: ArithmeticCategory,
+		Description: "counts the unordered selections of r items out of n.",
+		Params:      numberParams(IntParam, "n", "r"), Call: nkFunction(arithmetic.Combination)},
+	{Name: "ln", Category: ArithmeticCategory, Description: "computes the natural logarithm of x.",
+		Params: seriesParams(), Call: complexFunction(arithmetic.ComplexNaturalLog, preciseFunction(arithmetic.BigNaturalLog, seriesFunction(arithmetic.NaturalLog)))},
+	{Name: "log", Category: ArithmeticCategory, Description: "computes the base 10 logarithm of x.",
+		Params: seriesParams(), Call: complexFunction(arithmetic.ComplexLogBaseTen, preciseFunction(arithmetic.BigLogBaseTen, seriesFunction(arithmetic.LogBaseTen)))},
+	{Name: "exponent", Aliases: []string{"exp", "e"}, Category: ArithmeticCategory, Description: "computes e to the power of x.",
+		Params: seriesParams(), Call: complexFunction(exactComplex(arithmetic.ComplexExponent), preciseFunction(exactSeries(arithmetic.BigExponent), seriesFunction(infallible(arithmetic.Exponent))))},
+	{Name: "pi", Category: ArithmeticCategory, Description: "computes pi with n terms of its series.",
+		Params: piParams(), ExpressionOnly: true, Call: callPi},
+	{Name: "and", Category: ArithmeticCategory, Description: "computes the bitwise and of x and y.",
+		Params: numberParams(IntParam, "x", "y"), Call: operatorFunction("&")},
+	{Name: "or", Category: ArithmeticCategory, Description: "computes the bitwise or of x and y.",
+		Params: numberParams(IntParam, "x", "y"), Call: operatorFunction("|")},
+	{Name: "xor", Category: ArithmeticCategory, Description: "computes the bitwise exclusive or of x and y.",
+		Params: numberParams(IntParam, "x", "y"), Call: operatorFunction("xor")},
+	{Name: "not", Aliases: []string{"~"}, Category: ArithmeticCategory, Description: "flips every bit of x.",
+		Params: numberParams(IntParam, "x"), Call: callNot},
+	{Name: "shl", Category: ArithmeticCategory, Description: "shifts x left by n bits.",
+		Params: shiftParams(), Call: operatorFunction("<<")},
+	{Name: "shr", Category: ArithmeticCategory, Description: "shifts x right by n bits.",
+		Params: shiftParams(), Call: operatorFunction(">>")},
+	{Name: "rol", Category: ArithmeticCategory, Description: "rotates the bits of x left by n.",
+		Params: shiftParams(), Call: operatorFunction("rol")},
+	{Name: "ror", Category: ArithmeticCategory, Description: "rotates the bits of x right by n.",
+		Params: shiftParams(), Call: operatorFunction("ror")},
+
+	{Name: "sin", Category: TrigCategory, Description: "computes the sine of the angle x.",
+		Params: seriesParams(), Call: angleInput(complexFunction(exactComplex(trig.ComplexSine), preciseFunction(exactSeries(trig.BigSine), seriesFunction(infallible(trig.Sine)))))},
+	{Name: "cos", Category: TrigCategory, Description: "computes the cosine of the angle x.",
+		Params: seriesParams(), Call: angleInput(complexFunction(exactComplex(trig.ComplexCosine), preciseFunction(exactSeries(trig.BigCosine), seriesFunction(infallible(trig.Cosine)))))},
+	{Name: "tan", Category: TrigCategory, Description: "computes the tangent of the angle x.",
+		Params: seriesParams(), Call: angleInput(complexFunction(exactComplex(trig.ComplexTangent), preciseFunction(exactSeries(trig.BigTangent), seriesFunction(infallible(trig.Tangent)))))},
+	{Name: "arcsin", Category: TrigCategory, Description: "computes the angle whose sine is x.",
+		Params: seriesParams(), Call: angleOutput(complexFunction(trig.ComplexInverseSine, seriesFunction(trig.InverseSine)))},
+	{Name: "arccos", Category: TrigCategory, Description: "computes the angle whose cosine is x.",
+		Params: seriesParams(), Call: angleOutput(complexFunction(trig.ComplexInverseCosine, seriesFunction(trig.InverseCosine)))},
+	{Name: "arctan", Category: TrigCategory, Description: "computes the angle whose tangent is x.",
+		Params: seriesParams(), Call: angleOutput(complexFunction(trig.ComplexInverseTangent, preciseFunction(exactSeries(trig.BigInverseTangent), seriesFunction(infallible(trig.InverseTangent)))))},
+
+	{Name: "min", Category: StatsCategory, Description: "finds the smallest value of the data.",
+		Params: []Param{dataParam}, Variadic: true, Call: statsFunction(stats.Min)},
+	{Name: "max", Category: StatsCategory, Description: "finds the largest value of the data.",
+		Params: []Param{dataParam}, Variadic: true, Call: statsFunction(stats.Max)},
+	{Name: "mean", Category: StatsCategory, Description: "computes the mean of the data.",
+		Params: []Param{dataParam}, Variadic: true, Call: statsFunction(stats.Mean)},
+	{Name: "mode", Category: StatsCategory, Description: "finds the most common value of the data.",
+		Params: []Param{dataParam}, Variadic: true, Call: statsFunction(stats.Mode)},
+	{Name: "median", Category: StatsCategory, Description: "finds the middle value of the data.",
+		Params: []Param{dataParam}, Variadic: true, Call: statsFunction(stats.Median)},
+	{Name: "sum", Category: StatsCategory, Description: "adds up the data.",
+		Params: []Param{dataParam}, Variadic: true, Call: statsFunction(infallibleStats(stats.Sum))},
+	{Name: "sd", Aliases: []string{"standard deviation"}, Category: StatsCategory,
+		Description: "computes the standard deviation of the data.",
+		Params:      []Param{dataParam}, Variadic: true, Call: statsFunction(stats.StandardDeviation)},
+	{Name: "pdf", Aliases: []string{"probability density function"}, Category: StatsCategory,
+		Description: "evaluates the normal distribution fitted to the data at x.",
+		Params: []Param{dataParam, {Name: "x", Kind: FloatParam,
+			Description: "the point at which the pdf is evaluated."}},
+		Call: callPdf},
+}
+
+// numberParams returns required parameters of the same kind.
+func numberParams(kind ParamKind, names ...string) []Param {
+	params := make([]Param, len(names))
+	for i, name := range names {
+		params[i] = Param{Name: name, Kind: kind, Description: "an " + kind.String() + "."}
+		if kind == NumberParam {
+			params[i].Description = "an int or a float."
+		}
+	}
+	return params
+}
+
+// seriesParams returns the parameters of a function computed with a Taylor
+// Series: x and the optional number of terms n.
+func seriesParams() []Param {
+	return []Param{{Name: "x", Kind: FloatParam, Description: "the value to compute."}, termsParam}
+}
+
+// complexParams returns the parameter of a function on the parts of a complex
+// number.
+func complexParams() []Param {
+	return []Param{{Name: "z", Kind: FloatParam, Description: "a complex number, e.g. 3+4i."}}
+}
+
+// shiftParams returns the parameters of the shifts and rotations.
+func shiftParams() []Param {
+	params := numberParams(IntParam, "x", "n")
+	params[1].Description = "the number of bits."
+	params[1].Validate = nonNegative("n")
+	return params
+}
+
+// powerParams returns the parameters of pow, whose exponent is bounded like
+// the powers of big integers.
+func powerParams() []Param {
+	params := numberParams(NumberParam, "x", "y")
+	params[1].Validate = inRange("y", -MaxExponent, MaxExponent)
+	return params
+}
+
+// piParams returns the parameter of pi, the number of terms n.
+func piParams() []Param {
+	params := numberParams(IntParam, "n")
+	params[0].Validate = inRange("n", 0, MaxPiTerms)
+	return params
+}
+
+// inRange returns a validator rejecting numbers below min or above max.
+func inRange(name string, min, max int) func(v Value) error {
+	return func(v Value) error {
+		if x := v.AsFloat(); x < float64(min) || x > float64(max) {
+			return fmt.Errorf("%s must be between %d and %d", name, min, max)
+		}
+		return nil
+	}
+}
+
+// nonNegative returns a validator rejecting negative numbers.
+func nonNegative(name string) func(v Value) error {
+	return func(v Value) error {
+		if v.AsFloat() < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+		return nil
+	}
+}
+
+// requireInt returns the int held by v or an error naming the argument.
+func requireInt(v Value, name string) (int, error) {
+	x, ok := v.AsInt()
+	if !ok {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return x, nil
+}
+
+// requireNumber returns the float held by v or an error naming the argument.
+func requireNumber(v Value, name string) (float64, error) {
+	if !v.IsNumber() {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return v.AsFloat(), nil
+}
+
+func intFunction(f func(x, y int) int, op string) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		if args[0].Kind == IntKind && args[1].Kind == IntKind && !CurrentSession.Word.Enabled() && !BigIntsEnabled() &&
+			!intOverflows(op, args[0].Int, args[1].Int) {
+			return IntValue(f(args[0].Int, args[1].Int)), nil
+		}
+		return ApplyBinaryOperator(op, args[0], args[1])
+	}
+}
+
+// operatorFunction adapts a binary operator to a function of two arguments.
+func operatorFunction(op string) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		return ApplyBinaryOperator(op, args[0], args[1])
+	}
+}
+
+func callNot(args []Value) (Value, error) {
+	return BitwiseNotValue(args[0])
+}
+
+// nkFunction adapts permutation and combination. In a fixed word size the
+// result wraps like any other integer.
+func nkFunction(f func(n, r int) (int, error)) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		v, err := f(args[0].Int, args[1].Int)
+		if err != nil {
+			return Value{}, err
+		}
+		return IntValue(CurrentSession.Word.Wrap(v)), nil
+	}
+}
+
+func callFactorial(args []Value) (Value, error) {
+	return FactorialValue(args[0])
+}
+
+func callAbs(args []Value) (Value, error) {
+	switch args[0].Kind {
+	case IntKind:
+		if w := CurrentSession.Word; w.Enabled() && w.Wrap(args[0].Int) < 0 {
+			return wordNegate(w, w.Wrap(args[0].Int)), nil
+		} else if w.Enabled() {
+			return IntValue(w.Wrap(args[0].Int)), nil
+		}
+		return IntValue(arithmetic.Abs(args[0].Int)), nil
+	case FloatKind:
+		return FloatValue(arithmetic.AbsFloat(args[0].Float)), nil
+	case BigKind:
+		return BigValue(args[0].Big.Abs()), nil
+	case PreciseKind:
+		return PreciseValue(new(big.Float).Abs(args[0].Precise)), nil
+	case FracKind:
+		return FractionValue(args[0].Frac.Abs()), nil
+	case ComplexKind:
+		return FloatValue(arithmetic.Modulus(args[0].Complex)), nil
+	}
+	return Value{}, errors.New("x must be a number")
+}
+
+// callSqrt computes real square roots with HeronsSquareRoot, and those of
+// complex and negative numbers with ComplexSquareRoot.
+func callSqrt(args []Value) (Value, error) {
+	if args[0].Kind == ComplexKind || args[0].AsFloat() < 0 {
+		return complexResult(arithmetic.ComplexSquareRoot(args[0].AsComplex(), args[1].AsFloat()))
+	}
+	return floatResult(arithmetic.HeronsSquareRoot(args[0].AsFloat(), args[1].AsFloat()))
+}
+
+func callConjugate(args []Value) (Value, error) {
+	return ComplexValue(arithmetic.Conjugate(args[0].AsComplex())), nil
+}
+
+func callModulus(args []Value) (Value, error) {
+	return FloatValue(arithmetic.Modulus(args[0].AsComplex())), nil
+}
+
+func callArgument(args []Value) (Value, error) {
+	return FloatValue(arithmetic.Argument(args[0].AsComplex())), nil
+}
+
+func callPi(args []Value) (Value, error) {
+	if PrecisionEnabled() {
+		return PreciseValue(arithmetic.BigPi(args[0].Int, precisionBits())), nil
+	}
+	return FloatValue(arithmetic.Pi(args[0].Int)), nil
+}
+
+// floatResult wraps the result of a library function in a Value.
+func floatResult(v float64, err error) (Value, error) {
+	if err != nil {
+		return Value{}, err
+	}
+	return FloatValue(v), nil
+}
+
+// infallible adapts a series function that is defined for every x.
+func infallible(f func(x float64, n int) float64) func(x float64, n int) (float64, error) {
+	return func(x float64, n int) (float64, error) {
+		return f(x, n), nil
+	}
+}
+
+// seriesFunction adapts a function taking x and the number of Taylor Series
+// terms n.
+func seriesFunction(f func(x float64, n int) (float64, error)) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		return floatResult(f(args[0].AsFloat(), args[1].Int))
+	}
+}
+
+// collectData flattens the arguments of a stats function into a single data
+// set, so that both mean([1,2,3]) and mean(1,2,3) work.
+func collectData(args []Value) []float64 {
+	data := make([]float64, 0)
+	for _, arg := range args {
+		data = append(data, arg.AsList()...)
+	}
+	return data
+}
+
+// infallibleStats adapts a stats function that is defined on every data set.
+func infallibleStats(f func(data []float64) float64) func(data []float64) (float64, error) {
+	return func(data []float64) (float64, error) {
+		return f(data), nil
+	}
+}
+
+func statsFunction(f func(data []float64) (float64, error)) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		return floatResult(f(args[0].List))
+	}
+}
+
+func callPdf(args []Value) (Value, error) {
+	return floatResult(stats.NormalDistributionPdf(args[0].List, args[1].AsFloat()))
+}
+
+// Constants holds the named constants available in every expression.
+var Constants = map[string]Value{
+	"pi":          FloatValue(math.Pi),
+	"e":           FloatValue(math.E),
+	ImaginaryUnit: ComplexValue(1i),
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/**
+This file contains the command history of the interactive prompt. Every line
+typed at a terminal is appended to a history file in the user's config
+directory so that it survives between sessions.
+*/
+
+// MaxHistoryEntries is the number of lines loaded from the history file.
+const MaxHistoryEntries = 1000
+
+// History is the list of lines typed in interactive sessions.
+type History struct {
+	entries []string
+	path    string
+}
+
+// HistoryPath returns the location of the history file.
+func HistoryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sine-uh-calculator", "history"), nil
+}
+
+// LoadHistory reads the history file. If it cannot be read the returned
+// history is still usable but is only kept in memory.
+func LoadHistory() (*History, error) {
+	h := &History{}
+	path, err := HistoryPath()
+	if err != nil {
+		return h, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return h, err
+	}
+	h.path = path
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		h.entries = append(h.entries, scanner.Text())
+	}
+	if len(h.entries) > MaxHistoryEntries {
+		h.entries = h.entries[len(h.entries)-MaxHistoryEntries:]
+	}
+	return h, scanner.Err()
+}
+
+// Entries returns every line in the history, oldest first.
+func (h *History) Entries() []string {
+	if h == nil {
+		return nil
+	}
+	return h.entries
+}
+
+// Add appends a line to the history and to the history file. Blank lines are
+// not recorded.
+func (h *History) Add(line string) {
+	line = strings.TrimSpace(line)
+	if h == nil || line == "" {
+		return
+	}
+	h.entries = append(h.entries, line)
+	if h.path == "" {
+		return
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
+
+// Expand replaces !! with the last line in the history and !n with the n-th
+// line. Any other line is returned unchanged.
+func (h *History) Expand(line string) (string, error) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "!") || trimmed == "!" {
+		return line, nil
+	}
+	entries := h.Entries()
+	if trimmed == "!!" {
+		if len(entries) == 0 {
+			return "", fmt.Errorf("history is empty")
+		}
+		return entries[len(entries)-1], nil
+	}
+	n, err := strconv.Atoi(trimmed[1:])
+	if err != nil {
+		return line, nil
+	}
+	if n < 1 || n > len(entries) {
+		return "", fmt.Errorf("no history entry %d", n)
+	}
+	return entries[n-1], nil
+}
+
+// Print lists the history with the numbers used by !n.
+func (h *History) Print() {
+	entries := h.Entries()
+	if len(entries) == 0 {
+		fmt.Println("History is empty.")
+		return
+	}
+	for i, entry := range entries {
+		fmt.Printf("%5d  %s\n", i+1, entry)
+	}
+	fmt.Println("===============================================================")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/**
+This file contains the inspect command, which shows how a float64 is stored:
+
+    inspect              the last result
+    inspect sin(0.5)     any expression
+
+It prints the sign, exponent and mantissa bits, the hex representation, the
+neighbouring float64 values and the size of one unit in the last place (ULP),
+which is the smallest possible rounding error at that value.
+*/
+
+// Bit layout of a float64.
+const (
+	float64ExponentBits = 11
+	float64MantissaBits = 52
+	float64ExponentBias = 1023
+)
+
+// FloatInspection describes the representation of a float64.
+type FloatInspection struct {
+	Value    float64
+	Bits     uint64
+	Sign     uint64
+	Exponent uint64
+	Mantissa uint64
+	Class    string
+	Previous float64
+	Next     float64
+	ULP      float64
+}
+
+// InspectFloat breaks x into its IEEE-754 fields and finds its neighbours.
+func InspectFloat(x float64) FloatInspection {
+	bits := math.Float64bits(x)
+	f := FloatInspection{
+		Value:    x,
+		Bits:     bits,
+		Sign:     bits >> (float64ExponentBits + float64MantissaBits),
+		Exponent: bits >> float64MantissaBits & (1<<float64ExponentBits - 1),
+		Mantissa: bits & (1<<float64MantissaBits - 1),
+		Previous: math.Nextafter(x, math.Inf(-1)),
+		Next:     math.Nextafter(x, math.Inf(1)),
+	}
+	switch {
+	case math.IsNaN(x):
+		f.Class = "NaN"
+	case math.IsInf(x, 0):
+		f.Class = "infinity"
+	case x == 0:
+		f.Class = "zero"
+	case f.Exponent == 0:
+		f.Class = "subnormal"
+	default:
+		f.Class = "normal"
+	}
+	switch {
+	case math.IsNaN(x) || math.IsInf(x, 0):
+		f.ULP = math.NaN()
+	case math.IsInf(f.Next, 0):
+		f.ULP = x - f.Previous
+	case x < 0:
+		f.ULP = x - f.Previous
+	default:
+		f.ULP = f.Next - x
+	}
+	return f
+}
+
+// UnbiasedExponent returns the power of two the mantissa is scaled by.
+// Subnormal numbers and zero use the smallest normal exponent.
+func (f FloatInspection) UnbiasedExponent() int {
+	if f.Exponent == 0 {
+		return 1 - float64ExponentBias
+	}
+	return int(f.Exponent) - float64ExponentBias
+}
+
+// ExecuteInspectCommand inspects the value of an expression, or the last
+// result when none is given.
+func ExecuteInspectCommand(args []string) error {
+	input := AnswerVariable
+	if len(args) > 0 {
+		input = strings.Join(args, " ")
+	}
+	v, err := EvaluateExpression(strings.ToLower(input), CurrentSession.Scope)
+	if err != nil {
+		return err
+	}
+	if !v.IsNumber() {
+		return errors.New("only numbers can be inspected")
+	}
+	f := InspectFloat(v.AsFloat())
+	if JSONOutputEnabled() {
+		r := ResultRecord{Expression: input, Inspection: f.JSON()}
+		r.SetResult(v)
+		PrintResultRecord(r)
+		return nil
+	}
+	PrintFloatInspection(input, f)
+	return nil
+}
+
+// JSON returns the fields of f as a JSON object. The bits are written as hex
+// strings, as JSON numbers cannot hold every uint64 exactly.
+func (f FloatInspection) JSON() map[string]interface{} {
+	return map[string]interface{}{
+		"hex":      fmt.Sprintf("0x%016x", f.Bits),
+		"sign":     f.Sign,
+		"exponent": f.UnbiasedExponent(),
+		"mantissa": fmt.Sprintf("0x%013x", f.Mantissa),
+		"class":    f.Class,
+		"previous": jsonFloat(f.Previous),
+		"next":     jsonFloat(f.Next),
+		"ulp":      jsonFloat(f.ULP),
+	}
+}
+
+// PrintFloatInspection prints every field of f.
+func PrintFloatInspection(input string, f FloatInspection) {
+	sign := "+"
+	if f.Sign == 1 {
+		sign = "-"
+	}
+	exponent := fmt.Sprintf("%0*b", float64ExponentBits, f.Exponent)
+	switch f.Class {
+	case "normal":
+		exponent += fmt.Sprintf("  (%d - %d = %d)", f.Exponent, float64ExponentBias, f.UnbiasedExponent())
+	case "subnormal":
+		exponent += fmt.Sprintf("  (subnormal, %d)", f.UnbiasedExponent())
+	}
+	fmt.Printf("inspect %s\n", input)
+	fmt.Printf("  value      %s\n", strconv.FormatFloat(f.Value, 'g', -1, 64))
+	fmt.Printf("  decimal    %.17g\n", f.Value)
+	fmt.Printf("  hex        0x%016x  (%x)\n", f.Bits, f.Value)
+	fmt.Printf("  sign       %d  (%s)\n", f.Sign, sign)
+	fmt.Printf("  exponent   %s\n", exponent)
+	fmt.Printf("  mantissa   %0*b\n", float64MantissaBits, f.Mantissa)
+	fmt.Printf("  class      %s\n", f.Class)
+	fmt.Printf("  previous   %s\n", strconv.FormatFloat(f.Previous, 'g', -1, 64))
+	fmt.Printf("  next       %s\n", strconv.FormatFloat(f.Next, 'g', -1, 64))
+	fmt.Printf("  ulp        %s\n", strconv.FormatFloat(f.ULP, 'g', -1, 64))
+	fmt.Println("===============================================================")
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+/**
+This file contains the line editor used when the calculator is attached to a
+terminal. It puts the terminal in raw mode while a line is typed so that the
+line can be edited and earlier lines recalled from the history.
+
+    left / right, Ctrl-B / Ctrl-F    move the cursor
+    home / end, Ctrl-A / Ctrl-E      move to the start or end of the line
+    up / down                        recall older or newer lines
+    backspace / delete               delete before or under the cursor
+    Ctrl-K / Ctrl-U                  delete to the end or start of the line
+    Ctrl-W                           delete the word before the cursor
+    tab                              complete a name, twice lists candidates
+*/
+
+// Keys handled by the line editor.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyTab       = '\t'
+	keyNewline   = '\n'
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = '\r'
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// LineEditor holds the state of a line being typed at a terminal.
+type LineEditor struct {
+	reader     *bufio.Reader
+	prompt     string
+	line       []rune
+	cursor     int
+	history    []string
+	index      int
+	draft      []rune
+	candidates func() []string
+	lastTab    bool
+}
+
+// ReadLine shows prompt and reads a line of input. At a terminal the line is
+// read by a LineEditor, otherwise it is read as is and no prompt is shown.
+func ReadLine(reader *bufio.Reader, prompt string) (string, error) {
+	if !CurrentSession.Interactive {
+		return reader.ReadString('\n')
+	}
+	restore, err := EnableRawMode(os.Stdin)
+	if err != nil {
+		fmt.Print(prompt)
+		return reader.ReadString('\n')
+	}
+	defer restore()
+	history := CurrentSession.History.Entries()
+	editor := &LineEditor{
+		reader:     reader,
+		prompt:     prompt,
+		history:    history,
+		index:      len(history),
+		candidates: CompletionCandidates,
+	}
+	return editor.Run()
+}
+
+// Run reads keys until the line is submitted with enter. Ctrl-C discards the
+// line and Ctrl-D on an empty line ends the input.
+func (e *LineEditor) Run() (string, error) {
+	e.redraw()
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			return string(e.line), err
+		}
+		tab := r == keyTab
+		switch r {
+		case keyEnter, keyNewline:
+			fmt.Print("\r\n")
+			return string(e.line), nil
+		case keyCtrlC:
+			fmt.Print("^C\r\n")
+			return "", nil
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				fmt.Print("\r\n")
+				return "", io.EOF
+			}
+			e.deleteRange(e.cursor, e.cursor+1)
+		case keyCtrlA:
+			e.cursor = 0
+		case keyCtrlE:
+			e.cursor = len(e.line)
+		case keyCtrlB:
+			e.moveCursor(-1)
+		case keyCtrlF:
+			e.moveCursor(1)
+		case keyCtrlK:
+			e.deleteRange(e.cursor, len(e.line))
+		case keyCtrlU:
+			e.deleteRange(0, e.cursor)
+		case keyCtrlW:
+			e.deleteRange(e.wordStart(), e.cursor)
+		case keyCtrlL:
+			fmt.Print("\x1b[H\x1b[2J")
+		case keyBackspace, keyDelete:
+			e.deleteRange(e.cursor-1, e.cursor)
+		case keyTab:
+			e.complete()
+		case keyEscape:
+			e.handleEscapeSequence()
+		default:
+			if r >= ' ' {
+				e.insert([]rune{r})
+			}
+		}
+		e.lastTab = tab
+		e.redraw()
+	}
+}
+
+// handleEscapeSequence reads the rest of an escape sequence such as the up
+// arrow, ESC [ A, or the delete key, ESC [ 3 ~.
+func (e *LineEditor) handleEscapeSequence() {
+	if r, _, err := e.reader.ReadRune(); err != nil || (r != '[' && r != 'O') {
+		return
+	}
+	// Parameters such as the 3 in ESC [ 3 ~ come before the final character.
+	params := ""
+	r, _, err := e.reader.ReadRune()
+	for err == nil && (r < '@' || r > '~') {
+		params += string(r)
+		r, _, err = e.reader.ReadRune()
+	}
+	if err != nil {
+		return
+	}
+	if r == '~' {
+		r = []rune(params + " ")[0]
+	}
+	switch r {
+	case 'A':
+		e.recall(-1)
+	case 'B':
+		e.recall(1)
+	case 'C':
+		e.moveCursor(1)
+	case 'D':
+		e.moveCursor(-1)
+	case 'H', '1', '7':
+		e.cursor = 0
+	case 'F', '4', '8':
+		e.cursor = len(e.line)
+	case '3':
+		e.deleteRange(e.cursor, e.cursor+1)
+	}
+}
+
+// insert adds text at the cursor.
+func (e *LineEditor) insert(text []rune) {
+	line := make([]rune, 0, len(e.line)+len(text))
+	line = append(line, e.line[:e.cursor]...)
+	line = append(line, text...)
+	e.line = append(line, e.line[e.cursor:]...)
+	e.cursor += len(text)
+}
+
+// deleteRange removes the characters from start up to end, clamped to the
+// line.
+func (e *LineEditor) deleteRange(start, end int) {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(e.line) {
+		end = len(e.line)
+	}
+	if start >= end {
+		return
+	}
+	e.line = append(e.line[:start:start], e.line[end:]...)
+	e.cursor = start
+}
+
+func (e *LineEditor) moveCursor(step int) {
+	if cursor := e.cursor + step; cursor >= 0 && cursor <= len(e.line) {
+		e.cursor = cursor
+	}
+}
+
+// wordStart returns the position of the start of the space separated word
+// before the cursor, skipping any spaces directly before the cursor.
+func (e *LineEditor) wordStart() int {
+	i := e.cursor
+	for i > 0 && e.line[i-1] == ' ' {
+		i--
+	}
+	for i > 0 && e.line[i-1] != ' ' {
+		i--
+	}
+	return i
+}
+
+// complete completes the name before the cursor. If several names match, the
+// common prefix is completed and a second tab lists the candidates.
+func (e *LineEditor) complete() {
+	start := e.cursor
+	for start > 0 && isWordRune(e.line[start-1]) {
+		start--
+	}
+	prefix := string(e.line[start:e.cursor])
+	matches, common := Complete(prefix, e.candidates())
+	switch {
+	case len(matches) == 0:
+		fmt.Print("\a")
+	case len(matches) == 1:
+		e.insert([]rune(matches[0][len(prefix):] + " "))
+	case len(common) > len(prefix):
+		e.insert([]rune(common[len(prefix):]))
+	case e.lastTab:
+		fmt.Print("\r\n" + strings.Join(matches, "  ") + "\r\n")
+	default:
+		fmt.Print("\a")
+	}
+}
+
+// recall replaces the line with an older (step -1) or newer (step 1) line from
+// the history. The line being typed is kept so that it can be returned to.
+func (e *LineEditor) recall(step int) {
+	index := e.index + step
+	if index < 0 || index > len(e.history) {
+		return
+	}
+	if e.index == len(e.history) {
+		e.draft = e.line
+	}
+	e.index = index
+	if index == len(e.history) {
+		e.line = e.draft
+	} else {
+		e.line = []rune(e.history[index])
+	}
+	e.cursor = len(e.line)
+}
+
+// redraw prints the prompt and the line over the current terminal line and
+// puts the cursor back in place.
+func (e *LineEditor) redraw() {
+	fmt.Printf("\r%s%s\x1b[K", e.prompt, string(e.line))
+	if back := len(e.line) - e.cursor; back > 0 {
+		fmt.Printf("\x1b[%dD", back)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/**
+This file contains the memory registers of the calculator, which work like the
+M+, M-, MR and MC keys of a desk calculator. Besides the default register M any
+number of named registers can be used, e.g. m+ a adds ans to register a.
+*/
+
+// DefaultMemorySlot is the name of the default memory register M.
+const DefaultMemorySlot = ""
+
+// MemoryRecallName is the name used to recall a register at guided prompts and
+// in expressions, e.g. mr or mr(a).
+const MemoryRecallName = "mr"
+
+// MemoryAdd adds (sign 1) or subtracts (sign -1) ans to or from a register.
+func (s *Session) MemoryAdd(slot string, sign int) error {
+	ans, ok := s.Scope.Variables[AnswerVariable]
+	if !ok {
+		return errors.New("there is no result to store yet")
+	}
+	op := "+"
+	if sign < 0 {
+		op = "-"
+	}
+	v, err := ApplyBinaryOperator(op, s.Scope.Memory[slot], ans)
+	if err != nil {
+		return err
+	}
+	s.Scope.Memory[slot] = v
+	return nil
+}
+
+// MemoryRecall returns the value of a register. An empty register holds 0.
+func (s *Session) MemoryRecall(slot string) Value {
+	return s.Scope.Memory[slot]
+}
+
+// MemoryClear empties a register.
+func (s *Session) MemoryClear(slot string) {
+	delete(s.Scope.Memory, slot)
+}
+
+// MemorySlotName returns the name a register is displayed with.
+func MemorySlotName(slot string) string {
+	if slot == DefaultMemorySlot {
+		return "M"
+	}
+	return slot
+}
+
+// ExecuteMemoryCommand runs m+, m-, mr and mc, each optionally followed by the
+// name of a register.
+func ExecuteMemoryCommand(command string, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: %s [name]", command)
+	}
+	slot := DefaultMemorySlot
+	if len(args) == 1 {
+		slot = strings.ToLower(args[0])
+		if !IsIdentifier(slot) {
+			return fmt.Errorf("invalid register name %s, expected a name such as a or total", args[0])
+		}
+	}
+	switch command {
+	case "m+":
+		if err := CurrentSession.MemoryAdd(slot, 1); err != nil {
+			return err
+		}
+	case "m-":
+		if err := CurrentSession.MemoryAdd(slot, -1); err != nil {
+			return err
+		}
+	case "mr":
+		v := CurrentSession.MemoryRecall(slot)
+		CurrentSession.SetAnswer(v)
+		fmt.Printf("%s = %s\n", MemorySlotName(slot), v)
+	case "mc":
+		CurrentSession.MemoryClear(slot)
+	}
+	PrintMemoryStatus()
+	return nil
+}
+
+// PrintMemoryStatus prints the registers that hold a value, e.g.
+// [M = 12 | a = 3.50000]. Nothing is printed while all registers are empty.
+func PrintMemoryStatus() {
+	memory := CurrentSession.Scope.Memory
+	if len(memory) == 0 {
+		return
+	}
+	slots := make([]string, 0, len(memory))
+	for slot := range memory {
+		slots = append(slots, slot)
+	}
+	sort.Strings(slots)
+	registers := make([]string, len(slots))
+	for i, slot := range slots {
+		registers[i] = fmt.Sprintf("%s = %s", MemorySlotName(slot), memory[slot])
+	}
+	fmt.Printf("[%s]\n", strings.Join(registers, " | "))
+}
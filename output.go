@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/**
+This file contains the JSON output mode, set with output json at the prompt or
+--json on the command line. Every result is printed as a single line holding a
+JSON object instead of the boxed text, e.g.
+
+    {"function":"sin","inputs":{"x":0.25},"params":{"angle":"rad","n":9},
+     "result":0.24740395925452294,"display":"0.24740"}
+
+result holds the value at full precision and display the value as it would
+be printed in text mode. Failed commands print an object with an error.
+*/
+
+// OutputMode selects how results are printed.
+type OutputMode string
+
+const (
+	// TextOutput prints results as boxed text.
+	TextOutput OutputMode = "text"
+	// JSONOutput prints every result as a JSON object.
+	JSONOutput OutputMode = "json"
+)
+
+// ResultRecord is the JSON object printed for a result. Function is set for
+// calls to a named function and Expression for expressions and RPN lines.
+type ResultRecord struct {
+	Function   string                 `json:"function,omitempty"`
+	Expression string                 `json:"expression,omitempty"`
+	Inputs     map[string]interface{} `json:"inputs,omitempty"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Result     interface{}            `json:"result,omitempty"`
+	Display    string                 `json:"display,omitempty"`
+	Stack      []interface{}          `json:"stack,omitempty"`
+	Word       string                 `json:"word,omitempty"`
+	Bits       string                 `json:"bits,omitempty"`
+	Flags      []string               `json:"flags,omitempty"`
+	Inspection map[string]interface{} `json:"inspection,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// JSONOutputEnabled reports whether results are printed as JSON.
+func JSONOutputEnabled() bool {
+	return CurrentSession.Output == JSONOutput
+}
+
+// SetResult stores v in the record, along with its bit pattern and the flags
+// raised when a word size is set.
+func (r *ResultRecord) SetResult(v Value) {
+	r.Result = JSONValue(v)
+	r.Display = v.String()
+	if w := CurrentSession.Word; w.Enabled() && v.Kind == IntKind {
+		r.Word = w.String()
+		r.Bits = w.BitPattern(v.Int)
+		if flags := CurrentSession.Flags.String(); flags != "" {
+			r.Flags = strings.Fields(flags)
+		}
+	}
+}
+
+// JSONValue converts v to a value encoding/json can encode. Integers are
+// wrapped to the word size, complex numbers are written as an object of their
+// parts re and im, and infinities and NaN, which JSON numbers cannot hold, are
+// written as strings.
+func JSONValue(v Value) interface{} {
+	switch v.Kind {
+	case IntKind:
+		if w := CurrentSession.Word; w.Enabled() && !w.Signed {
+			return w.Pattern(v.Int)
+		}
+		return CurrentSession.Word.Wrap(v.Int)
+	case FloatKind:
+		return jsonFloat(v.Float)
+	case BigKind:
+		return json.Number(v.Big.String())
+	case PreciseKind:
+		return json.Number(FormatPrecise(v.Precise))
+	case FracKind:
+		return jsonFloat(v.Frac.Float())
+	case ComplexKind:
+		return map[string]interface{}{"re": jsonFloat(real(v.Complex)), "im": jsonFloat(imag(v.Complex))}
+	default:
+		list := make([]interface{}, len(v.List))
+		for i, x := range v.List {
+			list[i] = jsonFloat(x)
+		}
+		return list
+	}
+}
+
+func jsonFloat(x float64) interface{} {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	return x
+}
+
+// jsonInput converts an input typed at a prompt to a number, or a list of
+// numbers for data sets.
+func jsonInput(s string) interface{} {
+	s = strings.TrimSpace(s)
+	if x, err := ParseIntLiteral(s); err == nil {
+		return x
+	}
+	if x, err := strconv.ParseFloat(s, 64); err == nil {
+		return jsonFloat(x)
+	}
+	if IsFloatArrayString(s) {
+		return JSONValue(ListValue(ParseStringArrayToFloatArray(ParseInputToArray(s))))
+	}
+	return s
+}
+
+// PrintResultRecord prints r as a single line of JSON.
+func PrintResultRecord(r ResultRecord) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(r); err != nil {
+		encoder.Encode(ResultRecord{Error: err.Error()})
+	}
+}
+
+// ErrorRecord returns the JSON record of the line input that failed with err.
+// Functions called from their prompt are reported with their inputs, other
+// lines as the expression typed.
+func ErrorRecord(input string, err error) ResultRecord {
+	r := ResultRecord{Expression: strings.TrimSpace(input)}
+	var callErr *CallError
+	if errors.As(err, &callErr) {
+		r = callErr.Record
+	}
+	r.Error = err.Error()
+	return r
+}
+
+// jsonFields turns alternating names and inputs into a JSON object.
+func jsonFields(fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return nil
+	}
+	object := make(map[string]interface{}, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		object[fields[i]] = jsonInput(fields[i+1])
+	}
+	return object
+}
+
+// ParseOutputMode reads an output mode name.
+func ParseOutputMode(name string) (OutputMode, error) {
+	switch mode := OutputMode(strings.ToLower(name)); mode {
+	case TextOutput, JSONOutput:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown output mode %s, expected text or json", name)
+}
+
+// ExecuteOutputCommand shows the output mode of the session, or changes it
+// when one is given.
+func ExecuteOutputCommand(args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: output text|json")
+	}
+	if len(args) == 1 {
+		mode, err := ParseOutputMode(args[0])
+		if err != nil {
+			return err
+		}
+		CurrentSession.Output = mode
+	}
+	fmt.Printf("Output: %s.\n", CurrentSession.Output)
+	return nil
+}
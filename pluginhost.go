@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"calculator/plugins"
+	// Go plugins are linked in by importing them here, e.g.
+	//
+	//	_ "example.com/sensors/calibration"
+)
+
+/**
+This file adds the functions of plugins to the registry. There are two kinds of
+plugins:
+
+Go packages that call plugins.Register from an init function and are imported
+above.
+
+Executables in the plugins directory, see PluginDir, which are run once per
+request with a single JSON object on stdin and answer with a single JSON
+object on stdout:
+
+    -> {"method": "describe"}
+    <- {"functions": [{"name": "calibrate",
+                       "description": "converts a raw reading to Celsius.",
+                       "params": [{"name": "raw", "kind": "number"}]}]}
+
+    -> {"method": "call", "function": "calibrate", "args": {"raw": 1200}}
+    <- {"result": 35}    or    {"error": "raw must be positive"}
+
+The kind of a parameter is number, int or data, a data argument is an array of
+numbers. Parameters may also have a description and be optional with a
+default. Executables are described when the calculator starts, so the
+functions they provide are known to the prompt, help and the command line.
+*/
+
+// PluginTimeout is how long a plugin executable may take to answer.
+const PluginTimeout = 10 * time.Second
+
+// pluginKinds maps the kinds of plugin parameters to the kinds of the
+// registry.
+var pluginKinds = map[plugins.Kind]ParamKind{
+	plugins.Number: FloatParam,
+	plugins.Int:    IntParam,
+	plugins.Data:   DataParam,
+}
+
+// pluginKindNames maps the kinds of the executable protocol to plugin kinds.
+var pluginKindNames = map[string]plugins.Kind{
+	"":       plugins.Number,
+	"number": plugins.Number,
+	"int":    plugins.Int,
+	"data":   plugins.Data,
+}
+
+// settingFlags are the flags every command accepts, which optional parameters
+// cannot be named after.
+var settingFlags = []string{"format", "digits", "precision", "base", "word", "bigint", "frac", "json"}
+
+func init() {
+	for _, p := range plugins.Functions() {
+		if err := RegisterPlugin(p); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterPlugin adds a function of a plugin to the registry.
+func RegisterPlugin(p plugins.Function) error {
+	f, err := PluginFunction(p)
+	if err == nil {
+		err = RegisterFunction(f)
+	}
+	if err != nil {
+		return fmt.Errorf("plugin function %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// PluginFunction converts a function of a plugin to a registry entry. Optional
+// parameters are command-line flags named after the parameter.
+func PluginFunction(p plugins.Function) (*Function, error) {
+	if err := plugins.Validate(p); err != nil {
+		return nil, err
+	}
+	params := make([]Param, len(p.Params))
+	for i, param := range p.Params {
+		params[i] = Param{
+			Name:        param.Name,
+			Kind:        pluginKinds[param.Kind],
+			Description: param.Description,
+			Optional:    param.Optional,
+		}
+		if !param.Optional {
+			continue
+		}
+		if containsString(settingFlags, param.Name) {
+			return nil, fmt.Errorf("optional parameter %s clashes with the --%s flag", param.Name, param.Name)
+		}
+		params[i].Flags = []string{param.Name}
+		params[i].Default = FloatValue(param.Default)
+		if param.Kind == plugins.Int {
+			params[i].Default = IntValue(int(param.Default))
+		}
+	}
+	return &Function{
+		Name:        p.Name,
+		Aliases:     p.Aliases,
+		Category:    PluginCategory,
+		Description: p.Description,
+		Params:      params,
+		Call: func(args []Value) (Value, error) {
+			a := plugins.NewArgs()
+			for i, param := range p.Params {
+				if param.Kind == plugins.Data {
+					a.SetData(param.Name, args[i].List)
+				} else if args[i].Kind == ComplexKind {
+					return Value{}, fmt.Errorf("%s must be a real number", param.Name)
+				} else {
+					a.SetNumber(param.Name, args[i].AsFloat())
+				}
+			}
+			return floatResult(p.Call(a))
+		},
+	}, nil
+}
+
+// PluginDir returns the directory searched for plugin executables, which is
+// $CALCULATOR_PLUGINS if it is set.
+func PluginDir() (string, error) {
+	if dir := os.Getenv("CALCULATOR_PLUGINS"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sine-uh-calculator", "plugins"), nil
+}
+
+// LoadExternalPlugins registers the functions of every executable in the
+// plugins directory. Executables that cannot be described, or functions that
+// cannot be registered, are skipped and returned as errors.
+func LoadExternalPlugins() []error {
+	dir, err := PluginDir()
+	if err != nil {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return []error{err}
+	}
+	errs := make([]error, 0)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode()&0111 == 0 {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		functions, err := DescribeExternalPlugin(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", entry.Name(), err))
+			continue
+		}
+		for _, f := range functions {
+			if err := RegisterPlugin(f); err != nil {
+				errs = append(errs, fmt.Errorf("plugin %s: %w", entry.Name(), err))
+			}
+		}
+	}
+	return errs
+}
+
+// externalRequest is the JSON object written to a plugin executable.
+type externalRequest struct {
+	Method   string                 `json:"method"`
+	Function string                 `json:"function,omitempty"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+}
+
+// externalResponse is the JSON object a plugin executable answers with.
+type externalResponse struct {
+	Functions []externalFunction `json:"functions"`
+	Result    *float64           `json:"result"`
+	Error     string             `json:"error"`
+}
+
+// externalFunction describes a function of a plugin executable.
+type externalFunction struct {
+	Name        string          `json:"name"`
+	Aliases     []string        `json:"aliases"`
+	Description string          `json:"description"`
+	Params      []externalParam `json:"params"`
+}
+
+type externalParam struct {
+	Name        string  `json:"name"`
+	Kind        string  `json:"kind"`
+	Description string  `json:"description"`
+	Optional    bool    `json:"optional"`
+	Default     float64 `json:"default"`
+}
+
+// DescribeExternalPlugin asks a plugin executable for its functions.
+func DescribeExternalPlugin(path string) ([]plugins.Function, error) {
+	response, err := runExternalPlugin(path, externalRequest{Method: "describe"})
+	if err != nil {
+		return nil, err
+	}
+	functions := make([]plugins.Function, 0, len(response.Functions))
+	for _, f := range response.Functions {
+		p, err := externalPluginFunction(path, f)
+		if err != nil {
+			return nil, err
+		}
+		functions = append(functions, p)
+	}
+	return functions, nil
+}
+
+// externalPluginFunction converts the description of a function of a plugin
+// executable to a plugin function that calls the executable.
+func externalPluginFunction(path string, f externalFunction) (plugins.Function, error) {
+	params := make([]plugins.Param, len(f.Params))
+	for i, param := range f.Params {
+		kind, ok := pluginKindNames[param.Kind]
+		if !ok {
+			return plugins.Function{}, fmt.Errorf("%s: unknown kind %q of parameter %s", f.Name, param.Kind, param.Name)
+		}
+		params[i] = plugins.Param{
+			Name:        param.Name,
+			Kind:        kind,
+			Description: param.Description,
+			Optional:    param.Optional,
+			Default:     param.Default,
+		}
+	}
+	return plugins.Function{
+		Name:        f.Name,
+		Aliases:     f.Aliases,
+		Description: f.Description,
+		Params:      params,
+		Call: func(args plugins.Args) (float64, error) {
+			request := externalRequest{Method: "call", Function: f.Name, Args: make(map[string]interface{})}
+			for _, param := range params {
+				if param.Kind == plugins.Data {
+					request.Args[param.Name] = args.Data(param.Name)
+				} else {
+					request.Args[param.Name] = args.Number(param.Name)
+				}
+			}
+			response, err := runExternalPlugin(path, request)
+			switch {
+			case err != nil:
+				return 0, err
+			case response.Error != "":
+				return 0, errors.New(response.Error)
+			case response.Result == nil:
+				return 0, errors.New("plugin returned no result")
+			}
+			return *response.Result, nil
+		},
+	}, nil
+}
+
+// runExternalPlugin runs a plugin executable with a request and decodes its
+// response.
+func runExternalPlugin(path string, request externalRequest) (externalResponse, error) {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return externalResponse{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), PluginTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return externalResponse{}, fmt.Errorf("%s: %s", err, message)
+		}
+		return externalResponse{}, err
+	}
+	var response externalResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return externalResponse{}, fmt.Errorf("invalid response: %s", err)
+	}
+	return response, nil
+}
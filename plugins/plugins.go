@@ -0,0 +1,150 @@
+// Package plugins lets Go packages outside the calculator add functions to it.
+// A plugin registers its functions from an init function:
+//
+//	func init() {
+//		plugins.Register(plugins.Function{
+//			Name:        "calibrate",
+//			Description: "converts a raw sensor reading to degrees Celsius.",
+//			Params: []plugins.Param{
+//				{Name: "raw", Kind: plugins.Number, Description: "the raw reading."},
+//			},
+//			Call: func(args plugins.Args) (float64, error) {
+//				return 0.0625*args.Number("raw") - 40, nil
+//			},
+//		})
+//	}
+//
+// and is linked into the calculator with a blank import in pluginhost.go. Its
+// functions can then be used like any built-in function: at the prompt, in
+// expressions, in help, on the command line and through serve and rpc.
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Kind is the kind of value a parameter accepts.
+type Kind int
+
+const (
+	// Number parameters accept any number.
+	Number Kind = iota
+	// Int parameters accept whole numbers.
+	Int
+	// Data parameters accept data sets, e.g. 1,2,3 at a prompt.
+	Data
+)
+
+// Param describes an input of a function.
+type Param struct {
+	Name        string
+	Kind        Kind
+	Description string
+	// Optional parameters come last and take the value of Default when they
+	// are not given. Data parameters cannot be optional.
+	Optional bool
+	Default  float64
+}
+
+// Function is a function added to the calculator by a plugin.
+type Function struct {
+	Name        string
+	Aliases     []string
+	Description string
+	Params      []Param
+	// Call computes the result. The arguments have been checked against the
+	// parameters and the defaults of missing optional parameters filled in.
+	Call func(args Args) (float64, error)
+}
+
+// Args holds the arguments of a call by parameter name.
+type Args struct {
+	numbers map[string]float64
+	data    map[string][]float64
+}
+
+// NewArgs returns empty arguments.
+func NewArgs() Args {
+	return Args{make(map[string]float64), make(map[string][]float64)}
+}
+
+// SetNumber sets the argument of a Number or Int parameter.
+func (a Args) SetNumber(name string, x float64) {
+	a.numbers[name] = x
+}
+
+// SetData sets the argument of a Data parameter.
+func (a Args) SetData(name string, data []float64) {
+	a.data[name] = data
+}
+
+// Number returns the argument of a Number parameter.
+func (a Args) Number(name string) float64 {
+	return a.numbers[name]
+}
+
+// Int returns the argument of an Int parameter.
+func (a Args) Int(name string) int {
+	return int(a.numbers[name])
+}
+
+// Data returns the argument of a Data parameter.
+func (a Args) Data(name string) []float64 {
+	return a.data[name]
+}
+
+// ErrInvalidFunction is returned for functions that cannot be registered.
+var ErrInvalidFunction = errors.New("invalid plugin function")
+
+var (
+	mutex     sync.Mutex
+	functions []Function
+)
+
+// Register adds a function to the calculator. It panics if the function is
+// invalid, the same way registering a handler twice with net/http does.
+// Whether the name is already taken is checked when the calculator starts.
+func Register(f Function) {
+	if err := Validate(f); err != nil {
+		panic(err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	functions = append(functions, f)
+}
+
+// Functions returns the registered functions in the order they were
+// registered.
+func Functions() []Function {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return append([]Function(nil), functions...)
+}
+
+// Validate checks that a function has a name, an implementation and that its
+// optional parameters come last.
+func Validate(f Function) error {
+	if f.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidFunction)
+	}
+	if f.Call == nil {
+		return fmt.Errorf("%w: %s has no implementation", ErrInvalidFunction, f.Name)
+	}
+	optional := false
+	for _, p := range f.Params {
+		switch {
+		case p.Name == "":
+			return fmt.Errorf("%w: %s has a parameter without a name", ErrInvalidFunction, f.Name)
+		case p.Kind < Number || p.Kind > Data:
+			return fmt.Errorf("%w: %s has a parameter of unknown kind", ErrInvalidFunction, f.Name)
+		case p.Optional && p.Kind == Data:
+			return fmt.Errorf("%w: data parameter %s of %s cannot be optional", ErrInvalidFunction, p.Name, f.Name)
+		case optional && !p.Optional:
+			return fmt.Errorf("%w: optional parameters of %s must come last", ErrInvalidFunction, f.Name)
+		}
+		optional = p.Optional
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"calculator/arithmetic"
+)
+
+/**
+This file contains the precision setting, set with digits N, or its alias
+precision N, and cleared with digits off, or set with --precision N on the
+command line. While it is set, sin, cos, tan, arctan, exponent, ln, log and pi
+evaluate their series in arbitrary precision instead of float64, so that
+raising the number of terms n keeps yielding more correct digits, e.g.
+
+    >digits 50
+    >sin(1, 40)
+    >pi + e
+
+Decimal literals, the constants pi and e, and + - * / ^ on such results keep
+the precision, and results are printed with N significant digits. Everything
+else is still computed in floating point. The precision is unrelated to the
+digits of format, which only apply to float64 results.
+*/
+
+// MaxPrecisionDigits is the largest number of digits that can be asked for.
+const MaxPrecisionDigits = 1000
+
+// guardBits are the bits computed beyond the requested digits, which absorb
+// the rounding errors of the series.
+const guardBits = 32
+
+// PrecisionEnabled reports whether the series are evaluated in arbitrary
+// precision.
+func PrecisionEnabled() bool {
+	return CurrentSession.Precision > 0
+}
+
+// precisionBits returns the number of mantissa bits needed for the digits of
+// the session.
+func precisionBits() uint {
+	return uint(math.Ceil(float64(CurrentSession.Precision)*math.Log2(10))) + guardBits
+}
+
+// PreciseValue wraps an arbitrary-precision float in a Value.
+func PreciseValue(x *big.Float) Value {
+	return Value{Kind: PreciseKind, Precise: x}
+}
+
+// AsPrecise returns the numeric value of v at the precision of the session.
+func (v Value) AsPrecise() *big.Float {
+	x := new(big.Float).SetPrec(precisionBits())
+	switch v.Kind {
+	case IntKind:
+		return x.SetInt64(int64(v.Int))
+	case BigKind:
+		x.SetString(v.Big.String())
+		return x
+	case PreciseKind:
+		return x.Set(v.Precise)
+	}
+	return x.SetFloat64(v.AsFloat())
+}
+
+// isPreciseNumber reports whether v can be held by a big.Float, which has no
+// NaN.
+func isPreciseNumber(v Value) bool {
+	return v.Kind != FloatKind || !math.IsNaN(v.Float)
+}
+
+// FormatPrecise prints x with the number of digits of the session.
+func FormatPrecise(x *big.Float) string {
+	return x.Text('g', CurrentSession.Precision)
+}
+
+// parsePrecise reads a decimal literal such as 0.1 at the precision of the
+// session, so that it is not first rounded to a float64. It fails when the
+// precision is not set.
+func parsePrecise(text string) (Value, bool) {
+	if !PrecisionEnabled() {
+		return Value{}, false
+	}
+	x, _, err := big.ParseFloat(strings.TrimSpace(text), 10, precisionBits(), big.ToNearestEven)
+	if err != nil {
+		return Value{}, false
+	}
+	return PreciseValue(x), true
+}
+
+// preciseConstant returns pi or e at the precision of the session. It fails
+// when the precision is not set.
+func preciseConstant(name string) (Value, bool) {
+	if !PrecisionEnabled() {
+		return Value{}, false
+	}
+	terms := CurrentSession.Precision + 20
+	switch name {
+	case "pi":
+		return PreciseValue(arithmetic.BigPi(terms, precisionBits())), true
+	case "e":
+		one := new(big.Float).SetPrec(precisionBits()).SetInt64(1)
+		return PreciseValue(arithmetic.BigExponent(one, terms)), true
+	}
+	return Value{}, false
+}
+
+// applyPreciseOperator computes x op y in arbitrary precision. Powers are only
+// computed exactly for whole exponents, others fall back to floating point.
+func applyPreciseOperator(op string, x, y *big.Float) (Value, error) {
+	z := new(big.Float).SetPrec(precisionBits())
+	switch op {
+	case "+":
+		return PreciseValue(z.Add(x, y)), nil
+	case "-":
+		return PreciseValue(z.Sub(x, y)), nil
+	case "*":
+		return PreciseValue(z.Mul(x, y)), nil
+	case "/":
+		if y.Sign() == 0 {
+			return Value{}, ErrDivisionByZero
+		}
+		return PreciseValue(z.Quo(x, y)), nil
+	case "^":
+		power, accuracy := y.Int64()
+		if accuracy != big.Exact || power > math.MaxInt32 || power < math.MinInt32 {
+			a, _ := x.Float64()
+			b, _ := y.Float64()
+			return applyFloatOperator(op, a, b)
+		}
+		return PreciseValue(precisePower(x, int(power))), nil
+	}
+	return Value{}, fmt.Errorf("unknown operator %s", op)
+}
+
+// precisePower computes x^power by repeated squaring.
+func precisePower(x *big.Float, power int) *big.Float {
+	result := new(big.Float).SetPrec(precisionBits()).SetInt64(1)
+	base := new(big.Float).SetPrec(precisionBits()).Set(x)
+	for n := arithmetic.Abs(power); n > 0; n /= 2 {
+		if n%2 == 1 {
+			result.Mul(result, base)
+		}
+		base.Mul(base, base)
+	}
+	if power < 0 && result.Sign() != 0 {
+		result.Quo(new(big.Float).SetPrec(precisionBits()).SetInt64(1), result)
+	}
+	return result
+}
+
+// preciseSeries is a series evaluated in arbitrary precision, taking x and the
+// number of terms n.
+type preciseSeries func(x *big.Float, n int) (*big.Float, error)
+
+// exactSeries adapts a precise series that is defined for every x.
+func exactSeries(f func(x *big.Float, n int) *big.Float) preciseSeries {
+	return func(x *big.Float, n int) (*big.Float, error) {
+		return f(x, n), nil
+	}
+}
+
+// preciseFunction evaluates p while the precision is set and falls back to the
+// floating point implementation f otherwise.
+func preciseFunction(p preciseSeries, f func(args []Value) (Value, error)) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		if !PrecisionEnabled() {
+			return f(args)
+		}
+		v, err := p(args[0].AsPrecise(), args[1].Int)
+		if err != nil {
+			return Value{}, err
+		}
+		return PreciseValue(v), nil
+	}
+}
+
+// preciseAngle converts an angle x from one unit to another at the precision
+// of the session.
+func preciseAngle(x *big.Float, from, to AngleUnit) *big.Float {
+	halfTurn := map[AngleUnit]*big.Float{
+		Degrees:  big.NewFloat(180),
+		Gradians: big.NewFloat(200),
+		Radians:  arithmetic.BigPi(CurrentSession.Precision+20, precisionBits()),
+	}
+	z := new(big.Float).SetPrec(precisionBits()).Mul(x, halfTurn[to])
+	return z.Quo(z, halfTurn[from])
+}
+
+// ExecuteDigitsCommand shows the precision of the session, or changes it when
+// a number of digits or off is given.
+func ExecuteDigitsCommand(args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: digits N|off")
+	}
+	if len(args) == 1 {
+		digits, err := ParsePrecision(args[0])
+		if err != nil {
+			return err
+		}
+		CurrentSession.Precision = digits
+	}
+	if !PrecisionEnabled() {
+		fmt.Println("Precision: off, series are computed in float64.")
+		return nil
+	}
+	pi, _ := preciseConstant("pi")
+	fmt.Printf("Precision: %d digits, e.g. %s\n", CurrentSession.Precision, FormatPrecise(pi.Precise))
+	return nil
+}
+
+// ParsePrecision reads a number of digits between 1 and MaxPrecisionDigits,
+// or off, which is returned as 0.
+func ParsePrecision(s string) (int, error) {
+	if strings.ToLower(s) == "off" {
+		return 0, nil
+	}
+	digits, err := strconv.Atoi(s)
+	if err != nil || digits < 1 || digits > MaxPrecisionDigits {
+		return 0, fmt.Errorf("number of digits must be between 1 and %d, or off", MaxPrecisionDigits)
+	}
+	return digits, nil
+}
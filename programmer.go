@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/**
+This file contains the programmer mode of the calculator. Integers can be
+typed in hexadecimal, binary or octal with the prefixes 0x, 0b and 0o, and
+base changes the base integer results are displayed in:
+
+    base hex      255 is shown as 0xff
+    base all      255 is shown as 255 (0xff, 0o377, 0b11111111)
+    base dec      back to decimal
+
+Integers can be combined with and, or, xor, not, shl, shr, rol and ror, or
+with the operators & | ~ << >> in expressions.
+*/
+
+// NumberBase is a base in which integer results are displayed.
+type NumberBase string
+
+const (
+	// DecimalBase displays integers in base 10.
+	DecimalBase NumberBase = "dec"
+	// HexBase displays integers in base 16 with the prefix 0x.
+	HexBase NumberBase = "hex"
+	// OctalBase displays integers in base 8 with the prefix 0o.
+	OctalBase NumberBase = "oct"
+	// BinaryBase displays integers in base 2 with the prefix 0b.
+	BinaryBase NumberBase = "bin"
+	// AllBases displays integers in decimal followed by the other bases.
+	AllBases NumberBase = "all"
+)
+
+// ParseIntLiteral parses a decimal integer or a hexadecimal, binary or octal
+// integer with the prefix 0x, 0b or 0o, optionally preceded by a sign. A
+// leading zero without a prefix is still read as decimal.
+func ParseIntLiteral(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	digits := strings.TrimLeft(s, "+-")
+	if len(digits) > 2 && digits[0] == '0' && strings.ContainsRune("xXbBoO", rune(digits[1])) {
+		x, err := strconv.ParseInt(s, 0, 64)
+		return int(x), err
+	}
+	return strconv.Atoi(s)
+}
+
+// isRadixPrefix reports whether the runes at i start a 0x, 0b or 0o literal.
+func isRadixPrefix(runes []rune, i int) bool {
+	return runes[i] == '0' && i+2 < len(runes) &&
+		strings.ContainsRune("xXbBoO", runes[i+1]) && isWordRune(runes[i+2])
+}
+
+// scanRadixNumber returns the index just past the 0x, 0b or 0o literal
+// starting at i. Invalid digits are consumed so that they are reported as part
+// of the number.
+func scanRadixNumber(runes []rune, i int) int {
+	i += 2
+	for i < len(runes) && isWordRune(runes[i]) {
+		i++
+	}
+	return i
+}
+
+// FormatIntegerInBase formats x in base, e.g. -0xff.
+func FormatIntegerInBase(x int, base NumberBase) string {
+	sign := ""
+	magnitude := uint64(x)
+	if x < 0 {
+		sign = "-"
+		magnitude = -magnitude
+	}
+	switch base {
+	case HexBase, OctalBase, BinaryBase:
+		return sign + formatUnsignedInBase(magnitude, base)
+	case AllBases:
+		return fmt.Sprintf("%d (%s, %s, %s)", x, FormatIntegerInBase(x, HexBase),
+			FormatIntegerInBase(x, OctalBase), FormatIntegerInBase(x, BinaryBase))
+	default:
+		return strconv.Itoa(x)
+	}
+}
+
+// formatUnsignedInBase formats x in hex, oct or bin with its prefix.
+func formatUnsignedInBase(x uint64, base NumberBase) string {
+	switch base {
+	case HexBase:
+		return "0x" + strconv.FormatUint(x, 16)
+	case OctalBase:
+		return "0o" + strconv.FormatUint(x, 8)
+	default:
+		return "0b" + strconv.FormatUint(x, 2)
+	}
+}
+
+// FormatInteger formats x in the base of the session. When a word size is set
+// the other bases show the two's-complement bit pattern of x.
+func FormatInteger(x int) string {
+	if w := CurrentSession.Word; w.Enabled() {
+		return w.FormatInBase(x, CurrentSession.Base)
+	}
+	return FormatIntegerInBase(x, CurrentSession.Base)
+}
+
+// ParseNumberBase reads a base name such as hex.
+func ParseNumberBase(name string) (NumberBase, error) {
+	switch base := NumberBase(strings.ToLower(name)); base {
+	case DecimalBase, HexBase, OctalBase, BinaryBase, AllBases:
+		return base, nil
+	}
+	return "", fmt.Errorf("unknown base %s, expected dec, hex, oct, bin or all", name)
+}
+
+// ExecuteBaseCommand shows the base integers are displayed in, or changes it
+// when one is given.
+func ExecuteBaseCommand(args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: base dec|hex|oct|bin|all")
+	}
+	if len(args) == 1 {
+		base, err := ParseNumberBase(args[0])
+		if err != nil {
+			return err
+		}
+		CurrentSession.Base = base
+	}
+	fmt.Printf("Base: %s, e.g. %s\n", CurrentSession.Base, FormatInteger(255))
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+/**
+This file contains the prompt started by typing the name of a function at the
+main prompt. The inputs are asked for one at a time in the order of the
+function's parameters, and an empty line leaves an optional input at its
+default.
+*/
+
+// CallError is returned for a function called from its prompt that fails. It
+// holds the record of the call, so that the error can be reported along with
+// the inputs in JSON.
+type CallError struct {
+	Record ResultRecord
+	Err    error
+}
+
+func (e *CallError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// PromptFunctionAndCompute seeks the inputs of f, computes the result and
+// prints it.
+func PromptFunctionAndCompute(f *Function, reader *bufio.Reader) error {
+	PrintFunctionPromptHeader(f)
+
+	args := make([]Value, len(f.Params))
+	inputs := make([]string, len(f.Params))
+	for i, p := range f.Params {
+		p := p
+		input, err := ReadInput(reader, p.Name, p.Kind.String(), func(s string) bool {
+			return (p.Optional && s == "") || p.Kind.Accepts(s)
+		})
+		if err != nil {
+			return err
+		}
+		if input == "" {
+			args[i], inputs[i] = p.Default, p.DefaultString()
+			continue
+		}
+		args[i], inputs[i] = p.Kind.Parse(input), input
+	}
+
+	v, err := f.Invoke(args)
+	if err != nil {
+		return &CallError{promptRecord(f, inputs), err}
+	}
+	CurrentSession.SetAnswer(v)
+	PrintFunctionPromptResult(f, args, inputs, v)
+	return nil
+}
+
+// promptRecord returns the JSON record of a call of f from its prompt without
+// its result. inputs holds the text typed for each parameter.
+func promptRecord(f *Function, inputs []string) ResultRecord {
+	required, params := make([]string, 0), make([]string, 0)
+	for i, p := range f.Params {
+		if p.Optional {
+			params = append(params, p.Name, inputs[i])
+		} else {
+			required = append(required, p.Name, inputs[i])
+		}
+	}
+	if f.Category == TrigCategory {
+		params = append(params, "angle", string(CurrentSession.Angle))
+	}
+	return ResultRecord{Function: f.Name, Inputs: jsonFields(required), Params: jsonFields(params)}
+}
+
+// PrintFunctionPromptResult pretty prints the result of a function called from
+// its prompt. inputs holds the text typed for each parameter.
+func PrintFunctionPromptResult(f *Function, args []Value, inputs []string, v Value) {
+	if JSONOutputEnabled() {
+		r := promptRecord(f, inputs)
+		r.SetResult(v)
+		PrintResultRecord(r)
+		return
+	}
+	shown := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case arg.Kind == IntKind:
+			shown[i] = arg.String()
+		case f.Params[i].Kind == DataParam:
+			shown[i] = "[" + inputs[i] + "]"
+		default:
+			shown[i] = inputs[i]
+		}
+	}
+	fmt.Printf("%s(%s) = %s\n", f.Name, strings.Join(shown, ", "), v)
+	PrintWordStatus(v)
+	PrintMemoryStatus()
+	fmt.Println("===============================================================")
+}
+
+// PrintFunctionPromptHeader prints a pretty prompt explaining what f computes
+// and what it expects as input.
+func PrintFunctionPromptHeader(f *Function) {
+	if !CurrentSession.Interactive {
+		return
+	}
+	fmt.Println("===============================================================")
+	printBoxed("", fmt.Sprintf("%s %s", f.Name, f.Description))
+	for _, p := range f.Params {
+		description := p.Description
+		if p.Optional {
+			description += fmt.Sprintf(" Leave empty for %s.", p.DefaultString())
+		}
+		printBoxed(p.Name+": ", description)
+	}
+	if f.Category == TrigCategory {
+		printBoxed("", "Angles are in "+CurrentSession.Angle.Name()+".")
+	}
+	fmt.Println("===============================================================")
+}
+
+// printBoxed prints text inside the box of a prompt header, wrapping it at
+// word boundaries. Wrapped lines are indented to line up after label.
+func printBoxed(label, text string) {
+	const width = 59
+	line, words := label, 0
+	for _, word := range strings.Fields(text) {
+		if words > 0 && len(line)+1+len(word) > width {
+			fmt.Printf("| %-*s |\n", width, line)
+			line, words = strings.Repeat(" ", len(label)), 0
+		}
+		if words > 0 {
+			line += " "
+		}
+		line += word
+		words++
+	}
+	fmt.Printf("| %-*s |\n", width, line)
+}
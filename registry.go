@@ -0,0 +1,380 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"calculator/arithmetic"
+)
+
+/**
+This file contains the registry of the calculator's functions. Every Function
+in FunctionRegistry declares its name, aliases, parameters and implementation,
+and everything else is generated from it: calls in expressions and in RPN
+mode, the guided prompt started by typing a function's name, the function list
+of help, the command-line subcommands and the endpoints of serve and rpc.
+Adding a function only means adding an entry to FunctionRegistry in
+functions.go, or registering it from a plugin, see pluginhost.go.
+*/
+
+// ParamKind is the kind of value a parameter accepts.
+type ParamKind int
+
+const (
+	// IntParam parameters accept whole numbers.
+	IntParam ParamKind = iota
+	// NumberParam parameters accept integers and floats, integers are kept
+	// exact.
+	NumberParam
+	// FloatParam parameters accept any number and pass it on as a float, or
+	// as a complex number.
+	FloatParam
+	// DataParam parameters accept data sets, e.g. 1,2,3 at a prompt.
+	DataParam
+)
+
+// String returns the name of the kind shown when a value is asked for.
+func (k ParamKind) String() string {
+	switch k {
+	case IntParam:
+		return "int"
+	case NumberParam:
+		return "number"
+	case FloatParam:
+		return "float"
+	default:
+		return "array of floats"
+	}
+}
+
+// Accepts reports whether text typed at a prompt is a value of kind k.
+func (k ParamKind) Accepts(text string) bool {
+	switch k {
+	case IntParam:
+		return IsInt(text)
+	case NumberParam:
+		return IsInt(text) || IsFloat(text) || IsComplex(text)
+	case FloatParam:
+		return IsFloat(text) || IsComplex(text)
+	default:
+		return IsFloatArrayString(text)
+	}
+}
+
+// Parse converts text accepted by Accepts to a Value.
+func (k ParamKind) Parse(text string) Value {
+	if k == DataParam {
+		return ListValue(ParseStringArrayToFloatArray(ParseInputToArray(text)))
+	}
+	if x, err := ParseIntLiteral(text); err == nil && k != FloatParam {
+		return IntValue(x)
+	}
+	if x, err := arithmetic.ParseBigInt(text); err == nil && k == NumberParam && BigIntsEnabled() {
+		return BigValue(x)
+	}
+	if v, ok := parsePrecise(text); ok {
+		return v
+	}
+	if !IsFloat(text) {
+		z, _ := strconv.ParseComplex(strings.TrimSpace(text), 128)
+		return ComplexValue(z)
+	}
+	x, _ := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	return FloatValue(x)
+}
+
+// convert checks that v is of kind k and converts it, naming the parameter in
+// the error.
+func (k ParamKind) convert(v Value, name string) (Value, error) {
+	switch k {
+	case IntParam:
+		x, err := requireInt(v, name)
+		return IntValue(x), err
+	case NumberParam:
+		if !v.IsNumber() {
+			return Value{}, fmt.Errorf("%s must be a number", name)
+		}
+		return v, nil
+	case FloatParam:
+		if v.Kind == PreciseKind || v.Kind == ComplexKind {
+			return v, nil
+		}
+		x, err := requireNumber(v, name)
+		return FloatValue(x), err
+	default:
+		if v.Kind == ComplexKind {
+			return Value{}, fmt.Errorf("%s must not hold complex numbers", name)
+		}
+		return ListValue(v.AsList()), nil
+	}
+}
+
+// Param describes an input of a function.
+type Param struct {
+	Name        string
+	Kind        ParamKind
+	Description string
+	// Optional parameters come last and take the value of Default when they
+	// are not given.
+	Optional bool
+	Default  Value
+	// Flags are the command-line flags of an optional parameter, the first
+	// is the one shown in usage.
+	Flags []string
+	// Validate, if set, rejects values the function is not defined on.
+	Validate func(v Value) error
+}
+
+// DefaultString returns the default of an optional parameter at full
+// precision, the way it can be typed back, e.g. 1e-12, rather than in the
+// number format of the session.
+func (p Param) DefaultString() string {
+	if p.Default.Kind == FloatKind {
+		return strconv.FormatFloat(p.Default.Float, 'g', -1, 64)
+	}
+	return p.Default.String()
+}
+
+// Function is an entry of the registry.
+type Function struct {
+	Name        string
+	Aliases     []string
+	Category    string
+	Description string
+	Params      []Param
+	// Variadic functions take a single data set, which can also be given as
+	// any number of arguments, e.g. mean(1, 2, 3).
+	Variadic bool
+	// ExpressionOnly functions are not prompted for when their name is typed
+	// at the main prompt, e.g. pi, which is evaluated as the constant instead.
+	ExpressionOnly bool
+	// Call computes the result. The arguments have been checked against the
+	// parameters and the defaults of missing optional parameters filled in.
+	Call func(args []Value) (Value, error)
+}
+
+// Function categories, in the order they are listed by help. Each category
+// is also the path of its functions in the API, e.g. /v1/trig/sin.
+const (
+	ArithmeticCategory = "arithmetic"
+	TrigCategory       = "trig"
+	StatsCategory      = "stats"
+	PluginCategory     = "plugins"
+)
+
+// Categories lists the categories of the built-in functions and their titles
+// in help. Functions added by plugins are listed after them.
+var Categories = []struct {
+	Name  string
+	Title string
+}{
+	{ArithmeticCategory, "Arithmetic Functions"},
+	{TrigCategory, "Trigonometry Functions"},
+	{StatsCategory, "Statistical Functions"},
+}
+
+// functionsByName maps every name and alias in FunctionRegistry to its
+// function.
+var functionsByName = indexFunctions(FunctionRegistry)
+
+func indexFunctions(functions []*Function) map[string]*Function {
+	byName := make(map[string]*Function)
+	for _, f := range functions {
+		for _, name := range append([]string{f.Name}, f.Aliases...) {
+			if _, ok := byName[name]; ok {
+				panic("function registered twice: " + name)
+			}
+			byName[name] = f
+		}
+	}
+	return byName
+}
+
+// commandNames are the commands of the main prompt and the command line that
+// SessionCommands does not list.
+var commandNames = []string{"t", "test", "h", "bm", "functions", "eval", "run", "serve", "rpc"}
+
+// RegisterFunction adds a function to the registry. Names must be lower case
+// words, so that they can be typed at the prompt and called in expressions,
+// and must not be taken by another function, a constant or a command.
+func RegisterFunction(f *Function) error {
+	for _, name := range append([]string{f.Name}, f.Aliases...) {
+		if !isWord(name) || name != strings.ToLower(name) {
+			return fmt.Errorf("invalid function name %q", name)
+		}
+		if _, ok := functionsByName[name]; ok {
+			return fmt.Errorf("function %s is already defined", name)
+		}
+		if _, ok := Constants[name]; ok || containsString(SessionCommands, name) || containsString(commandNames, name) {
+			return fmt.Errorf("%s is a constant or command", name)
+		}
+	}
+	FunctionRegistry = append(FunctionRegistry, f)
+	for _, name := range append([]string{f.Name}, f.Aliases...) {
+		functionsByName[name] = f
+	}
+	return nil
+}
+
+// LookupFunction finds a function by its name or one of its aliases.
+func LookupFunction(name string) (*Function, bool) {
+	f, ok := functionsByName[name]
+	return f, ok
+}
+
+// FunctionNames returns every name and alias in the registry.
+func FunctionNames() []string {
+	names := make([]string, 0, len(functionsByName))
+	for name := range functionsByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FunctionsInCategory returns the functions of a category in the order they
+// are registered.
+func FunctionsInCategory(category string) []*Function {
+	functions := make([]*Function, 0)
+	for _, f := range FunctionRegistry {
+		if f.Category == category {
+			functions = append(functions, f)
+		}
+	}
+	return functions
+}
+
+// MinArgs returns the number of parameters that must be given.
+func (f *Function) MinArgs() int {
+	n := 0
+	for _, p := range f.Params {
+		if !p.Optional {
+			n++
+		}
+	}
+	return n
+}
+
+// MaxArgs returns the number of parameters that can be given, -1 for any
+// number.
+func (f *Function) MaxArgs() int {
+	if f.Variadic {
+		return -1
+	}
+	return len(f.Params)
+}
+
+// RequiredParams returns the parameters that must be given.
+func (f *Function) RequiredParams() []Param {
+	return f.Params[:f.MinArgs()]
+}
+
+// OptionalParams returns the parameters that can be left out.
+func (f *Function) OptionalParams() []Param {
+	return f.Params[f.MinArgs():]
+}
+
+// ArgumentError is returned by Invoke for arguments a function does not
+// accept, as opposed to errors of the computation itself.
+type ArgumentError struct {
+	Err error
+}
+
+func (e *ArgumentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ArgumentError) Unwrap() error {
+	return e.Err
+}
+
+func argumentError(format string, args ...interface{}) *ArgumentError {
+	return &ArgumentError{fmt.Errorf(format, args...)}
+}
+
+// Invoke checks the number and kinds of the arguments, fills in the defaults
+// of missing optional parameters and calls the function. Arguments that are
+// rejected are reported as ArgumentErrors.
+func (f *Function) Invoke(args []Value) (Value, error) {
+	if len(args) < f.MinArgs() || (f.MaxArgs() >= 0 && len(args) > f.MaxArgs()) {
+		return Value{}, argumentError("%s expects %s, got %d", f.Name, f.describeArity(), len(args))
+	}
+	if f.Variadic {
+		for _, arg := range args {
+			if arg.Kind == ComplexKind {
+				return Value{}, argumentError("%s must not hold complex numbers", f.Params[0].Name)
+			}
+		}
+		args = []Value{ListValue(collectData(args))}
+	}
+	converted := make([]Value, len(f.Params))
+	for i, p := range f.Params {
+		if i >= len(args) {
+			converted[i] = p.Default
+			continue
+		}
+		v, err := p.Kind.convert(args[i], p.Name)
+		if err != nil {
+			return Value{}, &ArgumentError{err}
+		}
+		if p.Validate != nil {
+			if err := p.Validate(v); err != nil {
+				return Value{}, &ArgumentError{err}
+			}
+		}
+		converted[i] = v
+	}
+	return f.Call(converted)
+}
+
+func (f *Function) describeArity() string {
+	min, max := f.MinArgs(), f.MaxArgs()
+	switch {
+	case max < 0:
+		return fmt.Sprintf("at least %d arguments", min)
+	case min == max && min == 1:
+		return "1 argument"
+	case min == max:
+		return fmt.Sprintf("%d arguments", min)
+	default:
+		return fmt.Sprintf("%d to %d arguments", min, max)
+	}
+}
+
+// CallBuiltin calls the registered function called name.
+func CallBuiltin(name string, args []Value) (Value, error) {
+	f, ok := LookupFunction(name)
+	if !ok {
+		return Value{}, fmt.Errorf("unknown function %s", name)
+	}
+	return f.Invoke(args)
+}
+
+// PrintFunctionList prints the functions of a category as part of help, e.g.
+//
+//	|    * add (+)          * subtract (-)     * multiply (*)         |
+func PrintFunctionList(category string) {
+	const columns, width = 3, 19
+	line, used := "", 0
+	flush := func() {
+		fmt.Printf("|    %-57s|\n", strings.TrimRight(line, " "))
+		line, used = "", 0
+	}
+	for _, f := range FunctionsInCategory(category) {
+		entry := "* " + f.Name
+		if len(f.Aliases) > 0 {
+			entry += " (" + strings.Join(f.Aliases, ", ") + ")"
+		}
+		cells := (len(entry) + width) / width
+		if used+cells > columns {
+			flush()
+		}
+		line += fmt.Sprintf("%-*s", cells*width, entry)
+		used += cells
+	}
+	if used > 0 {
+		flush()
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+/**
+This file contains calculator rpc, which speaks JSON-RPC 2.0 on stdin and
+stdout so that editors and notebooks can drive a long-lived calculator. Every
+line read is a request, a notification or a batch of them, and every response
+is written as a single line:
+
+    --> {"jsonrpc":"2.0","id":1,"method":"trig.sin","params":{"x":0.25,"n":9}}
+    <-- {"jsonrpc":"2.0","result":{"function":"sin",...,"result":0.2474...},"id":1}
+
+Methods:
+
+    arithmetic.add, trig.sin, stats.median, ...  call a function, params are
+                                                 named as in /v1/functions or
+                                                 given in that order
+    eval          {"expression": "x = 2^3"}      evaluate in the session
+    vars.list                                    every variable and its value
+    vars.get      {"name": "x"}                  the value of a variable
+    vars.set      {"name": "x", "value": 2}      store a variable
+    vars.delete   {"name": "x"}                  delete a variable or function
+    functions                                    list the function methods
+
+Results are the same objects printed by --json. Variables and functions
+defined with eval live as long as the process.
+*/
+
+// JSON-RPC 2.0 error codes.
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCComputeError   = -32000
+)
+
+// RPCVersion is the only version of JSON-RPC understood.
+const RPCVersion = "2.0"
+
+// RPCRequest is a request or, when it has no id, a notification.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// RPCResponse holds either the result of a request or the error it failed
+// with. The id is null when the request could not be read.
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// RPCError is the error object of a failed request.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+func rpcError(code int, format string, args ...interface{}) *RPCError {
+	return &RPCError{code, fmt.Sprintf(format, args...)}
+}
+
+// RunRPCCommand serves JSON-RPC requests until stdin is closed.
+func RunRPCCommand(args []string) int {
+	fs := flag.NewFlagSet(commandLineName+" rpc", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s rpc\n", commandLineName)
+		fs.PrintDefaults()
+	}
+	applySettings := addSettingFlags(fs)
+	positional, err := ParseInterleavedFlags(fs, args)
+	if err != nil {
+		return ExitUsageError
+	}
+	if err := applySettings(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, err)
+		return ExitUsageError
+	}
+	if len(positional) != 0 {
+		fs.Usage()
+		return ExitUsageError
+	}
+	if err := ServeRPC(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, err)
+		return ExitFailure
+	}
+	return ExitOk
+}
+
+// ServeRPC answers every line read from r on w.
+func ServeRPC(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if response := HandleRPCMessage(line); response != nil {
+			if _, err := w.Write(append(response, '\n')); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// HandleRPCMessage answers a request, a notification or a batch. It returns
+// nil when there is nothing to answer.
+func HandleRPCMessage(message []byte) []byte {
+	message = bytes.TrimSpace(message)
+	if len(message) == 0 {
+		return nil
+	}
+	if !json.Valid(message) {
+		return encodeRPC(RPCResponse{JSONRPC: RPCVersion, Error: rpcError(RPCParseError, "parse error")})
+	}
+	if message[0] != '[' {
+		if response := handleRPCRequest(message); response != nil {
+			return encodeRPC(*response)
+		}
+		return nil
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(message, &batch); err != nil || len(batch) == 0 {
+		return encodeRPC(RPCResponse{JSONRPC: RPCVersion, Error: rpcError(RPCInvalidRequest, "invalid request: empty batch")})
+	}
+	responses := make([]RPCResponse, 0, len(batch))
+	for _, request := range batch {
+		if response := handleRPCRequest(request); response != nil {
+			responses = append(responses, *response)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	return encodeRPC(responses)
+}
+
+// handleRPCRequest calls the method of a single request. Notifications are
+// called too but get no response.
+func handleRPCRequest(message json.RawMessage) *RPCResponse {
+	var request RPCRequest
+	if err := json.Unmarshal(message, &request); err != nil {
+		return &RPCResponse{JSONRPC: RPCVersion, Error: rpcError(RPCInvalidRequest, "invalid request: not a request object")}
+	}
+	if !validRPCID(request.ID) {
+		return &RPCResponse{JSONRPC: RPCVersion, Error: rpcError(RPCInvalidRequest, "invalid request: id must be a string or a number")}
+	}
+	response := &RPCResponse{JSONRPC: RPCVersion, ID: request.ID}
+	switch {
+	case request.JSONRPC != RPCVersion:
+		response.Error = rpcError(RPCInvalidRequest, "invalid request: jsonrpc must be %q", RPCVersion)
+	case request.Method == "":
+		response.Error = rpcError(RPCInvalidRequest, "invalid request: missing method")
+	default:
+		result, err := CallRPCMethod(request.Method, request.Params)
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) {
+			response.Error = rpcErr
+		} else if err != nil {
+			response.Error = rpcError(RPCComputeError, "%s", err)
+		} else {
+			response.Result = result
+		}
+	}
+	if request.ID == nil {
+		return nil
+	}
+	return response
+}
+
+// validRPCID reports whether id is absent, null, a string or a number.
+func validRPCID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	var v interface{}
+	json.Unmarshal(id, &v)
+	switch v.(type) {
+	case nil, string, float64:
+		return true
+	}
+	return false
+}
+
+// CallRPCMethod runs a method with its raw params and returns its result.
+// Params that cannot be used are reported as RPCErrors, anything else failing
+// is an error of the computation.
+func CallRPCMethod(method string, raw json.RawMessage) (interface{}, error) {
+	if f, ok := rpcFunction(method); ok {
+		body, err := rpcParams(raw, APIInputNames(f))
+		if err != nil {
+			return nil, err
+		}
+		v, err := CallAPIFunction(f, body, CurrentSession.Scope)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest {
+			return nil, rpcError(RPCInvalidParams, "%s", apiErr.Err)
+		} else if err != nil {
+			return nil, errors.Unwrap(err)
+		}
+		record := ResultRecord{Function: f.Name, Inputs: body}
+		record.SetResult(v)
+		return record, nil
+	}
+
+	switch method {
+	case "functions":
+		if _, err := rpcParams(raw, nil); err != nil {
+			return nil, err
+		}
+		functions := make(map[string][]string)
+		for _, f := range FunctionRegistry {
+			functions[f.Category+"."+f.Name] = APIInputNames(f)
+		}
+		return functions, nil
+	case "eval":
+		params, err := rpcParams(raw, []string{"expression"})
+		if err != nil {
+			return nil, err
+		}
+		expression, err := rpcString(params, "expression")
+		if err != nil {
+			return nil, err
+		}
+		return rpcEval(expression)
+	case "vars.list":
+		if _, err := rpcParams(raw, nil); err != nil {
+			return nil, err
+		}
+		variables := make(map[string]interface{})
+		for name, v := range CurrentSession.Scope.Variables {
+			variables[name] = JSONValue(v)
+		}
+		return variables, nil
+	case "vars.get":
+		params, err := rpcParams(raw, []string{"name"})
+		if err != nil {
+			return nil, err
+		}
+		name, err := rpcString(params, "name")
+		if err != nil {
+			return nil, err
+		}
+		v, ok := CurrentSession.Scope.Variables[strings.ToLower(name)]
+		if !ok {
+			return nil, rpcError(RPCInvalidParams, "%s is not defined", name)
+		}
+		record := ResultRecord{Expression: name}
+		record.SetResult(v)
+		return record, nil
+	case "vars.set":
+		params, err := rpcParams(raw, []string{"name", "value"})
+		if err != nil {
+			return nil, err
+		}
+		name, err := rpcString(params, "name")
+		if err != nil {
+			return nil, err
+		}
+		if !isVariableName(strings.ToLower(name)) {
+			return nil, rpcError(RPCInvalidParams, "%q is not a valid variable name", name)
+		}
+		input, ok := params["value"]
+		if !ok {
+			return nil, rpcError(RPCInvalidParams, "missing parameter value")
+		}
+		v, err := apiValue("value", input, CurrentSession.Scope)
+		if err != nil {
+			return nil, rpcError(RPCInvalidParams, "%s", errors.Unwrap(err))
+		}
+		CurrentSession.Scope.Variables[strings.ToLower(name)] = v
+		record := ResultRecord{Expression: name}
+		record.SetResult(v)
+		return record, nil
+	case "vars.delete":
+		params, err := rpcParams(raw, []string{"name"})
+		if err != nil {
+			return nil, err
+		}
+		name, err := rpcString(params, "name")
+		if err != nil {
+			return nil, err
+		}
+		if err := CurrentSession.Delete(strings.ToLower(name)); err != nil {
+			return nil, rpcError(RPCInvalidParams, "%s", err)
+		}
+		return true, nil
+	}
+	return nil, rpcError(RPCMethodNotFound, "method not found: %s", method)
+}
+
+// rpcEval evaluates an expression, assignment or function definition in the
+// session and stores its value in ans.
+func rpcEval(expression string) (interface{}, error) {
+	node, err := ParseStatement(strings.ToLower(expression))
+	if err != nil {
+		return nil, err
+	}
+	v, err := node.Eval(CurrentSession.Scope)
+	if err != nil {
+		return nil, err
+	}
+	record := ResultRecord{Expression: expression}
+	if definition, ok := node.(FunctionDefinition); ok {
+		record.Display = fmt.Sprintf("Defined %s", definition.Function)
+		return record, nil
+	}
+	CurrentSession.SetAnswer(v)
+	record.SetResult(v)
+	return record, nil
+}
+
+// isVariableName reports whether a value can be assigned to name.
+func isVariableName(name string) bool {
+	node, err := ParseStatement(name + " = 0")
+	assignment, ok := node.(AssignmentNode)
+	return err == nil && ok && assignment.Name == name
+}
+
+// rpcFunction finds the function called by a category.function method, e.g.
+// trig.sin.
+func rpcFunction(method string) (*Function, bool) {
+	category, name, ok := strings.Cut(method, ".")
+	if !ok {
+		return nil, false
+	}
+	return APIFunction(category, name)
+}
+
+// rpcParams decodes params given by name or, as an array, in the order of
+// names. Numbers are kept as json.Number so that integers stay integers.
+func rpcParams(raw json.RawMessage, names []string) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	if len(raw) == 0 {
+		return params, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, rpcError(RPCInvalidParams, "invalid params: %s", err)
+	}
+	switch p := v.(type) {
+	case map[string]interface{}:
+		for name := range p {
+			if !containsString(names, name) {
+				return nil, rpcError(RPCInvalidParams, "unknown parameter %s", name)
+			}
+		}
+		return p, nil
+	case []interface{}:
+		if len(p) > len(names) {
+			return nil, rpcError(RPCInvalidParams, "expected at most %d params, got %d", len(names), len(p))
+		}
+		for i, x := range p {
+			params[names[i]] = x
+		}
+		return params, nil
+	}
+	return nil, rpcError(RPCInvalidParams, "params must be an object or an array")
+}
+
+// rpcString returns the string parameter called name.
+func rpcString(params map[string]interface{}, name string) (string, error) {
+	s, ok := params[name].(string)
+	if !ok {
+		return "", rpcError(RPCInvalidParams, "%s must be a string", name)
+	}
+	return s, nil
+}
+
+func encodeRPC(v interface{}) []byte {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+	return bytes.TrimRight(buffer.Bytes(), "\n")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/**
+This file contains the Reverse Polish Notation mode, toggled with rpn. In this
+mode every word typed at the prompt either pushes a value onto the stack or
+operates on the values at the top of it, e.g. 3 4 + 2 * or 0.25 sin.
+
+    + - * / ^        binary operators, e.g. 3 4 - computes 3 - 4
+    & | << >>        bitwise operators
+    ~                bitwise not
+    p c              permutation and combination
+    !                factorial
+    dup              duplicate the top of the stack
+    swap             swap the two values at the top of the stack
+    drop             remove the top of the stack
+    roll             move the top of the stack to the bottom
+    clear            empty the stack
+
+Any other function name, including user-defined functions, pops as many values
+as the function requires. Stats functions such as mean first pop the number of
+values to use, like the counted groups of an HP calculator, e.g. 1 2 3 3 mean
+computes the mean of 1, 2 and 3. Numbers, constants, variables and mr push
+their value.
+*/
+
+// RPNStack is the stack of values operated on in RPN mode. The top of the stack
+// is the last element.
+type RPNStack []Value
+
+// ToggleRPN switches RPN mode on or off.
+func (s *Session) ToggleRPN() {
+	s.RPN = !s.RPN
+	if s.RPN {
+		fmt.Println("RPN mode on. Type rpn again to leave it.")
+		PrintRPNStack(s.Stack)
+		return
+	}
+	fmt.Println("RPN mode off.")
+}
+
+// IsRPNPassthroughCommand reports whether a line typed in RPN mode is a
+// session command, such as a setting or m+, rather than words for the stack.
+// mr is a word, which pushes the value of a register.
+func IsRPNPassthroughCommand(input string) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return true
+	}
+	switch fields[0] {
+	case "format", "base", "word", "bigint", "digits", "precision", "frac", "complex", "inspect", "output", "vars",
+		"funcs", "functions", "m+", "m-", "mc", "del", "delete", "source":
+		return true
+	}
+	return false
+}
+
+// ExecuteRPNLine runs every word of a line against the session's stack. If any
+// word fails, the stack is left as it was before the line.
+func ExecuteRPNLine(input string) error {
+	stack := append(RPNStack(nil), CurrentSession.Stack...)
+	for _, word := range strings.Fields(input) {
+		var err error
+		if stack, err = ExecuteRPNWord(stack, word, CurrentSession.Scope); err != nil {
+			return fmt.Errorf("%s: %s", word, err)
+		}
+	}
+	CurrentSession.Stack = stack
+	if len(stack) > 0 {
+		CurrentSession.SetAnswer(stack[len(stack)-1])
+	}
+	if JSONOutputEnabled() {
+		PrintRPNRecord(input, stack)
+		return nil
+	}
+	PrintRPNStack(stack)
+	return nil
+}
+
+// ExecuteRPNWord applies a single word to the stack and returns the new stack.
+func ExecuteRPNWord(stack RPNStack, word string, scope *Scope) (RPNStack, error) {
+	switch word {
+	case "dup":
+		x, err := stack.peek(1)
+		if err != nil {
+			return nil, err
+		}
+		return append(stack, x[0]), nil
+	case "swap":
+		x, err := stack.peek(2)
+		if err != nil {
+			return nil, err
+		}
+		return append(stack[:len(stack)-2], x[1], x[0]), nil
+	case "drop":
+		if _, err := stack.peek(1); err != nil {
+			return nil, err
+		}
+		return stack[:len(stack)-1], nil
+	case "roll":
+		if len(stack) < 2 {
+			return stack, nil
+		}
+		return append(RPNStack{stack[len(stack)-1]}, stack[:len(stack)-1]...), nil
+	case "clear":
+		return RPNStack{}, nil
+	case "+", "-", "*", "/", "^", "&", "|", "<<", ">>":
+		x, err := stack.peek(2)
+		if err != nil {
+			return nil, err
+		}
+		v, err := ApplyBinaryOperator(word, x[0], x[1])
+		if err != nil {
+			return nil, err
+		}
+		return append(stack[:len(stack)-2], v), nil
+	case "!":
+		word = "factorial"
+	case "~":
+		word = "not"
+	}
+
+	if f, ok := scope.Functions[word]; ok {
+		return stack.apply(len(f.Params), func(args []Value) (Value, error) {
+			return f.Call(args, scope)
+		})
+	}
+	if f, ok := LookupFunction(word); ok {
+		if f.Variadic {
+			return stack.applyCounted(f.Invoke)
+		}
+		return stack.apply(f.MinArgs(), f.Invoke)
+	}
+
+	v, err := EvaluateExpression(word, scope)
+	if err != nil {
+		return nil, err
+	}
+	return append(stack, v), nil
+}
+
+// peek returns the n values at the top of the stack, the top last.
+func (stack RPNStack) peek(n int) ([]Value, error) {
+	if len(stack) < n {
+		return nil, errors.New("not enough values on the stack")
+	}
+	return stack[len(stack)-n:], nil
+}
+
+// apply pops n values, passes them to f and pushes the result.
+func (stack RPNStack) apply(n int, f func(args []Value) (Value, error)) (RPNStack, error) {
+	if n == 0 && len(stack) == 0 {
+		return nil, errors.New("not enough values on the stack")
+	}
+	args, err := stack.peek(n)
+	if err != nil {
+		return nil, err
+	}
+	v, err := f(append([]Value(nil), args...))
+	if err != nil {
+		return nil, err
+	}
+	return append(stack[:len(stack)-n:len(stack)-n], v), nil
+}
+
+// applyCounted pops the number of values n from the top of the stack, then
+// passes the n values below it to f and pushes the result.
+func (stack RPNStack) applyCounted(f func(args []Value) (Value, error)) (RPNStack, error) {
+	count, err := stack.peek(1)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := count[0].AsInt()
+	if !ok || n < 1 {
+		return nil, errors.New("the top of the stack must be the number of values to use")
+	}
+	if n > len(stack)-1 {
+		return nil, fmt.Errorf("not enough values on the stack for a count of %d", n)
+	}
+	return stack[:len(stack)-1].apply(n, f)
+}
+
+// PrintRPNRecord prints the stack after a line as JSON, bottom first. The
+// result is the top of the stack.
+func PrintRPNRecord(input string, stack RPNStack) {
+	r := ResultRecord{Expression: input, Stack: make([]interface{}, len(stack))}
+	for i, v := range stack {
+		r.Stack[i] = JSONValue(v)
+	}
+	if len(stack) > 0 {
+		r.SetResult(stack[len(stack)-1])
+	}
+	PrintResultRecord(r)
+}
+
+// PrintRPNStack prints the stack with the top of the stack at the bottom,
+// numbered like the levels of an HP calculator.
+func PrintRPNStack(stack RPNStack) {
+	if len(stack) == 0 {
+		fmt.Println("(empty stack)")
+	}
+	for i, v := range stack {
+		fmt.Printf("%d: %s\n", len(stack)-i, v)
+	}
+	if len(stack) > 0 {
+		PrintWordStatus(stack[len(stack)-1])
+	}
+	PrintMemoryStatus()
+	fmt.Println("===============================================================")
+}
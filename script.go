@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+/**
+This file contains the script runner used by calculator run <file> and by the
+source command. A script holds one command or expression per line, exactly as
+it would be typed at the main prompt. Blank lines and lines starting with # are
+skipped.
+*/
+
+// MaxScriptDepth limits how deeply scripts can source other scripts.
+const MaxScriptDepth = 16
+
+var scriptDepth = 0
+
+// ScriptReader serves the lines of a script one at a time. Because a
+// bufio.Reader reading from it never receives more than one line per read, the
+// guided prompts can read their inputs from the lines following a command
+// while the reader still knows which line is being executed.
+type ScriptReader struct {
+	lines   []string
+	next    int
+	pending []byte
+}
+
+// NewScriptReader returns a ScriptReader over the contents of a script.
+func NewScriptReader(contents string) *ScriptReader {
+	return &ScriptReader{lines: strings.Split(contents, "\n")}
+}
+
+// Read implements io.Reader, skipping blank lines and comments.
+func (r *ScriptReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.next >= len(r.lines) {
+			return 0, io.EOF
+		}
+		line := strings.TrimSpace(StripComment(r.lines[r.next]))
+		r.next++
+		if line != "" {
+			r.pending = []byte(line + "\n")
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// LineNumber returns the number of the last line that was read.
+func (r *ScriptReader) LineNumber() int {
+	return r.next
+}
+
+// RunScript executes every line of the script at path. It stops at the first
+// line that fails unless keepGoing is set, in which case every failure is
+// reported and the script carries on.
+func RunScript(path string, keepGoing bool) error {
+	if scriptDepth >= MaxScriptDepth {
+		return fmt.Errorf("%s: scripts nested too deeply", path)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	scriptDepth++
+	interactive := CurrentSession.Interactive
+	CurrentSession.Interactive = false
+	defer func() {
+		scriptDepth--
+		CurrentSession.Interactive = interactive
+	}()
+
+	scriptReader := NewScriptReader(string(contents))
+	reader := bufio.NewReader(scriptReader)
+	failures := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		lineNumber := scriptReader.LineNumber()
+		if err := ParseAndExecute(line, reader); err != nil {
+			err = fmt.Errorf("%s:%d: %s", path, lineNumber, err)
+			if !keepGoing {
+				return err
+			}
+			if JSONOutputEnabled() {
+				PrintResultRecord(ErrorRecord(line, err))
+			} else {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			}
+			failures++
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("%s: %d lines failed", path, failures)
+	}
+	return nil
+}
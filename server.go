@@ -0,0 +1,382 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+This file contains the HTTP API started with calculator serve, e.g.
+
+    calculator serve --addr localhost:8080
+
+Every function of the calculator can be called with a POST request holding its
+inputs as a JSON object and every result is returned as the same JSON object
+printed by --json:
+
+    POST /v1/trig/sin        {"x": 0.25, "n": 9}
+    POST /v1/stats/median    {"data": [1, 2, 3]}
+    POST /v1/eval            {"expression": "2*pi"}
+    GET  /v1/functions       lists every function and its inputs
+
+Inputs may also be strings, which are evaluated as expressions, e.g.
+{"x": "pi/4"}. n is the number of Taylor Series terms, margin the margin of
+error of sqrt and angle the angle unit of trigonometry functions. Requests
+that cannot be understood or hold inputs a function does not accept, e.g. more
+than MaxTerms terms, get status 400, unknown functions 404 and computations
+that fail, e.g. a division by zero, 422. Computations are done one at a time
+and a request that gets no result within RequestTimeout gets status 503.
+*/
+
+// DefaultServerAddress is the address serve listens on when --addr is not
+// given. It only accepts connections from the local machine.
+const DefaultServerAddress = "localhost:8080"
+
+// RequestTimeout is the longest a request waits for its result, including the
+// time spent waiting for the computations of other requests.
+var RequestTimeout = 10 * time.Second
+
+// apiLock serializes computations, which read and write the settings of the
+// session. It is a channel rather than a mutex so that waiting for it can time
+// out.
+var apiLock = make(chan struct{}, 1)
+
+// APIError is an error together with the HTTP status it is reported with.
+type APIError struct {
+	Status int
+	Err    error
+}
+
+func (e *APIError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+func badRequest(format string, args ...interface{}) *APIError {
+	return &APIError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+}
+
+// RunServeCommand starts the HTTP API.
+func RunServeCommand(args []string) int {
+	fs := flag.NewFlagSet(commandLineName+" serve", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s serve [--addr host:port]\n", commandLineName)
+		fs.PrintDefaults()
+	}
+	addr := DefaultServerAddress
+	fs.StringVar(&addr, "addr", DefaultServerAddress, "`address` to listen on")
+	applySettings := addSettingFlags(fs)
+	positional, err := ParseInterleavedFlags(fs, args)
+	if err != nil {
+		return ExitUsageError
+	}
+	if err := applySettings(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, err)
+		return ExitUsageError
+	}
+	if len(positional) != 0 {
+		fs.Usage()
+		return ExitUsageError
+	}
+	fmt.Fprintf(os.Stderr, "%s: serving on http://%s/v1/\n", commandLineName, addr)
+	if err := http.ListenAndServe(addr, NewAPIHandler()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", commandLineName, err)
+		return ExitFailure
+	}
+	return ExitOk
+}
+
+// NewAPIHandler returns the handler serving the /v1/ endpoints.
+func NewAPIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/functions", handleFunctionList)
+	mux.HandleFunc("/v1/eval", handleEval)
+	mux.HandleFunc("/v1/", handleFunction)
+	return mux
+}
+
+// handleFunctionList lists every category, its functions and their inputs.
+func handleFunctionList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, ResultRecord{}, &APIError{http.StatusMethodNotAllowed, errors.New("use GET")})
+		return
+	}
+	type function struct {
+		Path   string   `json:"path"`
+		Inputs []string `json:"inputs"`
+	}
+	functions := make([]function, 0)
+	for _, f := range FunctionRegistry {
+		functions = append(functions, function{"/v1/" + f.Category + "/" + f.Name, APIInputNames(f)})
+	}
+	writeJSON(w, http.StatusOK, functions)
+}
+
+// handleEval evaluates {"expression": "..."}.
+func handleEval(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Expression *string `json:"expression"`
+	}
+	record := ResultRecord{}
+	if err := decodeAPIRequest(r, &body); err != nil {
+		writeAPIError(w, record, err)
+		return
+	}
+	if body.Expression == nil {
+		writeAPIError(w, record, badRequest("missing parameter expression"))
+		return
+	}
+	record.Expression = *body.Expression
+
+	v, err := computeWithTimeout(func() (Value, error) {
+		return EvaluateExpression(strings.ToLower(record.Expression), NewScope())
+	})
+	writeAPIResult(w, record, v, err)
+}
+
+// handleFunction calls the function named by a /v1/{category}/{function}
+// path.
+func handleFunction(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/"), "/"), "/")
+	var f *Function
+	if len(parts) == 2 {
+		f, _ = APIFunction(parts[0], parts[1])
+	}
+	if f == nil {
+		writeAPIError(w, ResultRecord{}, &APIError{http.StatusNotFound, fmt.Errorf("no function at %s", r.URL.Path)})
+		return
+	}
+	record := ResultRecord{Function: f.Name}
+
+	var body map[string]interface{}
+	if err := decodeAPIRequest(r, &body); err != nil {
+		writeAPIError(w, record, err)
+		return
+	}
+	record.Inputs = body
+
+	v, err := computeWithTimeout(func() (Value, error) {
+		return CallAPIFunction(f, body, NewScope())
+	})
+	writeAPIResult(w, record, v, err)
+}
+
+// computeWithTimeout runs compute once no other computation is running and
+// returns its result, or status 503 when it is not done within RequestTimeout.
+// A computation that times out keeps running to its end, but it gives up the
+// lock, so that it does not hold up the requests after it.
+func computeWithTimeout(compute func() (Value, error)) (Value, error) {
+	timeout := time.NewTimer(RequestTimeout)
+	defer timeout.Stop()
+	errTimeout := &APIError{http.StatusServiceUnavailable,
+		fmt.Errorf("no result within %s, try fewer terms or smaller inputs", RequestTimeout)}
+	select {
+	case apiLock <- struct{}{}:
+	case <-timeout.C:
+		return Value{}, errTimeout
+	}
+
+	type result struct {
+		v   Value
+		err error
+	}
+	var release sync.Once
+	unlock := func() { release.Do(func() { <-apiLock }) }
+	done := make(chan result, 1)
+	go func() {
+		defer unlock()
+		defer func() {
+			if p := recover(); p != nil {
+				done <- result{err: fmt.Errorf("computation failed: %v", p)}
+			}
+		}()
+		v, err := compute()
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-timeout.C:
+		unlock()
+		return Value{}, errTimeout
+	}
+}
+
+// CallAPIFunction calls a function with its inputs given as a decoded JSON
+// object. Expressions among the inputs are evaluated in scope. Inputs that
+// cannot be used or that the function rejects are reported with status 400
+// and computations that fail with status 422.
+func CallAPIFunction(f *Function, body map[string]interface{}, scope *Scope) (Value, error) {
+	args, angle, err := apiArguments(f, body, scope)
+	if err != nil {
+		return Value{}, err
+	}
+	previous := CurrentSession.Angle
+	CurrentSession.Angle = angle
+	defer func() { CurrentSession.Angle = previous }()
+	v, err := f.Invoke(args)
+	if err != nil {
+		return Value{}, apiComputationError(err)
+	}
+	return v, nil
+}
+
+// apiComputationError reports the arguments a function rejects with status
+// 400 and computations that fail with status 422.
+func apiComputationError(err error) *APIError {
+	var argErr *ArgumentError
+	if errors.As(err, &argErr) {
+		return &APIError{http.StatusBadRequest, err}
+	}
+	return &APIError{http.StatusUnprocessableEntity, err}
+}
+
+// APIFunction finds the function served at /v1/{category}/{name}. Only the
+// name of a function is served, not its aliases.
+func APIFunction(category, name string) (*Function, bool) {
+	f, ok := LookupFunction(name)
+	if !ok || f.Name != name || f.Category != category {
+		return nil, false
+	}
+	return f, true
+}
+
+// APIInputNames returns the names of the inputs of a function in the order
+// they are passed to it, followed by angle for trigonometry functions.
+func APIInputNames(f *Function) []string {
+	names := make([]string, 0, len(f.Params)+1)
+	for _, p := range f.Params {
+		names = append(names, p.Name)
+	}
+	if f.Category == TrigCategory {
+		names = append(names, "angle")
+	}
+	return names
+}
+
+// apiArguments converts the JSON inputs of a function to its arguments in
+// order. Trigonometry functions also return the angle unit requested.
+func apiArguments(f *Function, body map[string]interface{}, scope *Scope) ([]Value, AngleUnit, error) {
+	names := APIInputNames(f)
+	for name := range body {
+		if !containsString(names, name) {
+			return nil, "", badRequest("unknown parameter %s", name)
+		}
+	}
+	angle := CurrentSession.Angle
+	if f.Category == TrigCategory {
+		if name, ok := body["angle"].(string); ok && IsAngleUnit(name) {
+			angle = AngleUnit(name)
+		} else if ok || body["angle"] != nil {
+			return nil, "", badRequest("angle must be deg, rad or grad")
+		}
+	}
+
+	args := make([]Value, 0, len(f.Params))
+	for _, p := range f.Params {
+		input, ok := body[p.Name]
+		if !ok {
+			if p.Optional {
+				break
+			}
+			return nil, "", badRequest("missing parameter %s", p.Name)
+		}
+		v, err := apiValue(p.Name, input, scope)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, v)
+	}
+	return args, angle, nil
+}
+
+// apiValue converts a JSON input to a Value. Strings are evaluated as
+// expressions in scope and arrays are data sets.
+func apiValue(name string, input interface{}, scope *Scope) (Value, error) {
+	switch x := input.(type) {
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return IntValue(int(n)), nil
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return Value{}, badRequest("invalid %s: %s", name, err)
+		}
+		return FloatValue(f), nil
+	case string:
+		v, err := EvaluateExpression(strings.ToLower(x), scope)
+		if err != nil {
+			return Value{}, badRequest("invalid %s: %s", name, err)
+		}
+		return v, nil
+	case []interface{}:
+		data := make([]float64, len(x))
+		for i, element := range x {
+			v, err := apiValue(name, element, scope)
+			if err != nil || !v.IsNumber() {
+				return Value{}, badRequest("%s must be an array of numbers", name)
+			}
+			data[i] = v.AsFloat()
+		}
+		return ListValue(data), nil
+	}
+	return Value{}, badRequest("%s must be a number, an expression or an array of numbers", name)
+}
+
+// decodeAPIRequest reads the JSON body of a POST request into v.
+func decodeAPIRequest(r *http.Request, v interface{}) error {
+	if r.Method != http.MethodPost {
+		return &APIError{http.StatusMethodNotAllowed, errors.New("use POST")}
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return badRequest("invalid JSON body: %s", err)
+	}
+	return nil
+}
+
+// writeAPIResult writes the result of a computation, or the reason it failed.
+// Errors that are not APIErrors already are reported like those of Invoke.
+func writeAPIResult(w http.ResponseWriter, record ResultRecord, v Value, err error) {
+	var apiErr *APIError
+	if err != nil && !errors.As(err, &apiErr) {
+		err = apiComputationError(err)
+	}
+	if err != nil {
+		writeAPIError(w, record, err)
+		return
+	}
+	record.SetResult(v)
+	writeJSON(w, http.StatusOK, record)
+}
+
+// writeAPIError writes record with the error. Errors other than APIErrors are
+// reported as internal server errors.
+func writeAPIError(w http.ResponseWriter, record ResultRecord, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		status = apiErr.Status
+	}
+	record.Error = err.Error()
+	writeJSON(w, status, record)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+}
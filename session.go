@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/**
+This file contains the state that is kept between commands typed at the main
+prompt, such as named variables, user-defined functions and the ans register.
+*/
+
+// AnswerVariable is the name of the variable holding the last result.
+const AnswerVariable = "ans"
+
+// Session holds everything the calculator remembers between commands.
+// Interactive is set when input comes from a person at a terminal rather than
+// from a pipe or a script, Failures counts the commands that failed. History is
+// only kept for interactive sessions. RPN is set while the prompt is in RPN mode
+// and Stack holds the values of that mode. Angle is the unit trigonometry
+// functions work in, Format the way floating point results are displayed and
+// Base the base integer results are displayed in. Word is the word size integers
+// are wrapped to and Flags the flags raised by the command being executed.
+// BigInts is set in the big integer mode, Precision is the number of digits
+// series are computed to, 0 for float64, Fractions the fraction mode and Complex
+// the form complex results are displayed in. Output selects between text and
+// JSON results.
+type Session struct {
+	Scope       *Scope
+	Interactive bool
+	Failures    int
+	History     *History
+	RPN         bool
+	Stack       RPNStack
+	Angle       AngleUnit
+	Format      NumberFormat
+	Base        NumberBase
+	Word        WordSize
+	Flags       WordFlags
+	BigInts     bool
+	Precision   int
+	Fractions   FractionMode
+	Complex     ComplexForm
+	Output      OutputMode
+}
+
+// NewSession returns an empty session.
+func NewSession() *Session {
+	return &Session{Scope: NewScope(), Angle: Radians, Format: DefaultNumberFormat, Base: DecimalBase, Fractions: FractionsOff, Complex: RectangularForm, Output: TextOutput}
+}
+
+// CurrentSession is the session used by the main prompt.
+var CurrentSession = NewSession()
+
+// SetAnswer stores v in the ans register.
+func (s *Session) SetAnswer(v Value) {
+	s.Scope.Variables[AnswerVariable] = v
+}
+
+// ReportError prints the reason a command failed and counts the failure. When
+// the session is not interactive errors go to stderr, so that results can be
+// piped into other programs. In JSON output mode errors are printed as JSON
+// objects along with the results, together with the line input that failed.
+func (s *Session) ReportError(input string, err error) {
+	s.Failures++
+	if s.Output == JSONOutput {
+		PrintResultRecord(ErrorRecord(input, err))
+		return
+	}
+	if s.Interactive {
+		fmt.Printf("ERROR: %s\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+}
+
+// ExitStatus returns ExitFailure if any command failed and ExitOk otherwise.
+func (s *Session) ExitStatus() int {
+	if s.Failures > 0 {
+		return ExitFailure
+	}
+	return ExitOk
+}
+
+// ClearVariables drops every variable, including ans.
+func (s *Session) ClearVariables() {
+	s.Scope.Variables = make(map[string]Value)
+}
+
+// ExpandInput replaces a variable name or a memory recall such as mr or mr a
+// typed at a guided prompt with the value it holds, so that names can be used
+// anywhere a number or data set is asked for. Any other input is returned
+// unchanged.
+func (s *Session) ExpandInput(input string) string {
+	name := strings.ToLower(strings.TrimSpace(input))
+	v, ok := s.Scope.Variables[name]
+	if fields := strings.Fields(name); len(fields) > 0 && fields[0] == MemoryRecallName && len(fields) <= 2 {
+		slot := DefaultMemorySlot
+		if len(fields) == 2 {
+			slot = fields[1]
+		}
+		v, ok = s.MemoryRecall(slot), true
+	}
+	if !ok {
+		return input
+	}
+	switch v.Kind {
+	case IntKind:
+		return strconv.Itoa(v.Int)
+	case FloatKind:
+		return strconv.FormatFloat(v.Float, 'g', -1, 64)
+	case BigKind:
+		return v.Big.String()
+	case PreciseKind:
+		return v.Precise.Text('g', -1)
+	case FracKind:
+		return strconv.FormatFloat(v.Frac.Float(), 'g', -1, 64)
+	case ComplexKind:
+		return strconv.FormatComplex(v.Complex, 'g', -1, 128)
+	default:
+		elements := make([]string, len(v.List))
+		for i, x := range v.List {
+			elements[i] = strconv.FormatFloat(x, 'g', -1, 64)
+		}
+		return strings.Join(elements, ",")
+	}
+}
+
+// PrintVariables lists every variable in the session in alphabetical order.
+func (s *Session) PrintVariables() {
+	if len(s.Scope.Variables) == 0 {
+		fmt.Println("No variables defined.")
+		return
+	}
+	names := make([]string, 0, len(s.Scope.Variables))
+	for name := range s.Scope.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s = %s\n", name, s.Scope.Variables[name])
+	}
+	fmt.Println("===============================================================")
+}
+
+// Delete removes the variable or user-defined function called name.
+func (s *Session) Delete(name string) error {
+	_, isVariable := s.Scope.Variables[name]
+	_, isFunction := s.Scope.Functions[name]
+	if !isVariable && !isFunction {
+		return fmt.Errorf("%s is not defined", name)
+	}
+	delete(s.Scope.Variables, name)
+	delete(s.Scope.Functions, name)
+	return nil
+}
+
+// ExecuteSessionCommand runs commands that take arguments, such as del f or
+// source file. It reports whether input was such a command and, if so, whether
+// it failed.
+func ExecuteSessionCommand(input string) (bool, error) {
+	fields := strings.Fields(input)
+	switch strings.ToLower(fields[0]) {
+	case "del", "delete":
+		if len(fields) < 2 {
+			return true, errors.New("usage: del <name> ...")
+		}
+		for _, name := range fields[1:] {
+			name = strings.ToLower(name)
+			if err := CurrentSession.Delete(name); err != nil {
+				return true, err
+			}
+			fmt.Printf("Deleted %s.\n", name)
+		}
+		return true, nil
+	case "m+", "m-", "mr", "mc":
+		return true, ExecuteMemoryCommand(strings.ToLower(fields[0]), fields[1:])
+	case "format":
+		return true, ExecuteFormatCommand(fields[1:])
+	case "base":
+		return true, ExecuteBaseCommand(fields[1:])
+	case "word":
+		return true, ExecuteWordCommand(fields[1:])
+	case "bigint":
+		return true, ExecuteBigIntCommand(fields[1:])
+	case "digits", "precision":
+		return true, ExecuteDigitsCommand(fields[1:])
+	case "frac":
+		return true, ExecuteFractionCommand(fields[1:])
+	case "complex":
+		return true, ExecuteComplexCommand(fields[1:])
+	case "output":
+		return true, ExecuteOutputCommand(fields[1:])
+	case "inspect":
+		return true, ExecuteInspectCommand(fields[1:])
+	case "source":
+		if len(fields) != 2 {
+			return true, errors.New("usage: source <file>")
+		}
+		return true, RunScript(fields[1], false)
+	}
+	return false, nil
+}
@@ -1,54 +1,81 @@
-package main
+// Package stats implements descriptive statistics over a set of data values
+// and the normal distribution. Functions that are undefined for an empty data
+// set return ErrEmptyData.
+package stats
 
 import (
+	"errors"
 	"math"
+
+	"calculator/arithmetic"
+)
+
+// Errors returned for data sets a function is not defined on.
+var (
+	ErrEmptyData     = errors.New("data must not be empty")
+	ErrZeroDeviation = errors.New("standard deviation of data must not be zero")
 )
 
 // Min returns the smallest element in a slice
-func Min(data []float64) float64 {
+func Min(data []float64) (float64, error) {
+	if len(data) == 0 {
+		return 0, ErrEmptyData
+	}
 	minSoFar := math.Inf(1)
 	for _, value := range data {
 		if value < minSoFar {
 			minSoFar = value
 		}
 	}
-	return minSoFar
+	return minSoFar, nil
 }
 
 // Max returns the largest element in a slice.
-func Max(data []float64) float64 {
+func Max(data []float64) (float64, error) {
+	if len(data) == 0 {
+		return 0, ErrEmptyData
+	}
 	maxSoFar := math.Inf(-1)
 	for _, value := range data {
 		if value > maxSoFar {
 			maxSoFar = value
 		}
 	}
-	return maxSoFar
+	return maxSoFar, nil
 }
 
 // Mean returns the average value of all elements in a slice.
-func Mean(data []float64) float64 {
+func Mean(data []float64) (float64, error) {
+	if len(data) == 0 {
+		return 0, ErrEmptyData
+	}
 	numberOfDataPoints := float64(len(data))
 	sum := Sum(data)
-	return sum / numberOfDataPoints
+	return sum / numberOfDataPoints, nil
 }
 
 // Variance returns the expectation of the squared deviation of a random
 // variable from its mean given a set of data values.
-func Variance(data []float64) float64 {
-	mean := Mean(data)
+func Variance(data []float64) (float64, error) {
+	mean, err := Mean(data)
+	if err != nil {
+		return 0, err
+	}
 	diffFromMeanSquaredSum := 0.0
 	for _, value := range data {
-		diffFromMeanSquaredSum += ToThePowerFloat(value-mean, 2)
+		diffFromMeanSquaredSum += arithmetic.ToThePowerFloat(value-mean, 2)
 	}
-	return diffFromMeanSquaredSum / float64(len(data))
+	return diffFromMeanSquaredSum / float64(len(data)), nil
 }
 
 // StandardDeviation returns the amount of variation or dispersion of a set of
 // data values.
-func StandardDeviation(data []float64) float64 {
-	variance := Variance(data)
-	return HeronsSquareRoot(variance, 0.00001)
+func StandardDeviation(data []float64) (float64, error) {
+	variance, err := Variance(data)
+	if err != nil {
+		return 0, err
+	}
+	return arithmetic.HeronsSquareRoot(variance, 0.00001)
 }
 
 // QuickSort sorts a set of data values in place. Theory behind QuickSort can
@@ -89,14 +116,18 @@ func QuickSortPartition(data []float64, low, high int) int {
 }
 
 // Median returns the value separating the higher half from the lower half of a
-// data sample.
-func Median(data []float64) float64 {
+// data sample. data is left unsorted.
+func Median(data []float64) (float64, error) {
 	n := len(data)
+	if n == 0 {
+		return 0, ErrEmptyData
+	}
+	data = append([]float64(nil), data...)
 	QuickSort(data)
 	if n%2 == 0 {
-		return (data[n/2] + data[(n/2)-1]) / 2.0
+		return (data[n/2] + data[(n/2)-1]) / 2.0, nil
 	}
-	return data[n/2]
+	return data[n/2], nil
 }
 
 // Sum returns the sum of all elements in a set of data values.
@@ -109,7 +140,10 @@ func Sum(data []float64) float64 {
 }
 
 // Mode returns the value that appears most often in a set of data values.
-func Mode(data []float64) float64 {
+func Mode(data []float64) (float64, error) {
+	if len(data) == 0 {
+		return 0, ErrEmptyData
+	}
 	frequencyMap := make(map[float64]int)
 	for _, dataPoint := range data {
 		frequencyMap[dataPoint]++
@@ -121,21 +155,36 @@ func Mode(data []float64) float64 {
 			mode = dataPoint
 		}
 	}
-	return mode
+	return mode, nil
 }
 
 // NormalDistributionPdf computes and returns the probability density function
 // of a data set at x i.e. pdf(x) in a normal distribution.
 // Y = { 1/[ σ * sqrt(2π) ] } * e^-((x - μ)^2)/(2*σ^2)
-func NormalDistributionPdf(data []float64, x float64) float64 {
-	return NormalDistributionPdfHelper(Mean(data), StandardDeviation(data), x)
+func NormalDistributionPdf(data []float64, x float64) (float64, error) {
+	mean, err := Mean(data)
+	if err != nil {
+		return 0, err
+	}
+	standardDeviation, err := StandardDeviation(data)
+	if err != nil {
+		return 0, err
+	}
+	return NormalDistributionPdfHelper(mean, standardDeviation, x)
 }
 
 // NormalDistributionPdfHelper is a helper function for NormalDistributionPdf
 // which computes pdf(x) given mean, sd and x.
-func NormalDistributionPdfHelper(mean, standardDeviation, x float64) float64 {
-	n := 1.0 / HeronsSquareRoot(standardDeviation*standardDeviation*2*math.Pi, 0.00001)
+func NormalDistributionPdfHelper(mean, standardDeviation, x float64) (float64, error) {
+	if !(standardDeviation > 0) {
+		return 0, ErrZeroDeviation
+	}
+	root, err := arithmetic.HeronsSquareRoot(standardDeviation*standardDeviation*2*math.Pi, 0.00001)
+	if err != nil {
+		return 0, err
+	}
+	n := 1.0 / root
 	ex := -1.0 * (((x - mean) * (x - mean)) / (2 * standardDeviation * standardDeviation))
 	y := math.Pow(math.E, ex)
-	return n * y
+	return n * y, nil
 }
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// EnableRawMode is only supported on Linux. Elsewhere input is read a line at
+// a time without editing.
+func EnableRawMode(f *os.File) (func(), error) {
+	return nil, errors.New("raw mode is not supported on this platform")
+}
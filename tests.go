@@ -1,8 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"calculator/arithmetic"
+	"calculator/plugins"
+	"calculator/stats"
+	"calculator/trig"
 )
 
 // RunTests run tests for all three key components of calculator.
@@ -10,6 +25,25 @@ func RunTests() {
 	TestArithmeticFunctions()
 	TestTrigonometryFunctions()
 	TestStatsFunctions()
+	TestExpressionEvaluator()
+	TestRPN()
+	TestAngleModes()
+	TestNumberFormats()
+	TestProgrammerMode()
+	TestWordSizes()
+	TestFloatInspection()
+	TestJSONOutput()
+	TestAPIServer()
+	TestRPC()
+	TestFunctionRegistry()
+	TestCommandLine()
+	TestScripts()
+	TestLineEditor()
+	TestPlugins()
+	TestBigIntegers()
+	TestPrecision()
+	TestFractions()
+	TestComplex()
 }
 
 // TestArithmeticFunctions runs tests on all Arithmetic function
@@ -17,27 +51,50 @@ func TestArithmeticFunctions() {
 	fmt.Println("===============================================================")
 	fmt.Println("| Running Arithmetic Tests ...                                |")
 
-	AssertOrPanicInt(Addition(24, 89, 34), 147)
-	AssertOrPanicInt(BitwiseAdd(24, 89), 113)
-	AssertOrPanicInt(Subtraction(89, 34, 21), 34)
-	AssertOrPanicInt(BitwiseSubtract(89, 24), 65)
-	AssertOrPanicInt(BitwiseSubtractFast(89, 24), 65)
-	AssertOrPanicInt(LongMultiplication(24, 89), 2136)
-	AssertOrPanicInt(KaratsubaMultiplicationFast(24, 89), 2136)
-	AssertOrPanicInt(LongDivision(89, 24), 3)
-	AssertOrPanicInt(Permutation(8, 4), 1680)
-	AssertOrPanicInt(Combination(8, 4), 70)
-	AssertOrPanic(Factorial(9), 362880)
-	AssertOrPanic(Pi(500000), math.Pi)
-	AssertOrPanicInt(Abs(-1), 1)
-	AssertOrPanic(AbsFloat(-1.0), 1.0)
-	AssertOrPanicInt(ToThePowerInt(2, 5), 32)
-	AssertOrPanic(ToThePowerFloat(2, 5), 32)
-	AssertOrPanic(NewtonianSquareRoot(9, 0.00001), 3)
-	AssertOrPanic(HeronsSquareRoot(9, 0.00001), 3)
-	AssertOrPanic(Exponent(1, 15), math.E)
-	AssertLogIsClose(NaturalLog(2.5, 15), 0.91629073187)
-	AssertLogIsClose(LogBaseTen(2.5, 15), 0.39794000867)
+	AssertOrPanicInt(arithmetic.Addition(24, 89, 34), 147)
+	AssertOrPanicInt(arithmetic.BitwiseAdd(24, 89), 113)
+	AssertOrPanicInt(arithmetic.Subtraction(89, 34, 21), 34)
+	AssertOrPanicInt(arithmetic.BitwiseSubtract(89, 24), 65)
+	AssertOrPanicInt(arithmetic.BitwiseSubtractFast(89, 24), 65)
+	AssertOrPanicInt(arithmetic.LongMultiplication(24, 89), 2136)
+	AssertOrPanicInt(arithmetic.KaratsubaMultiplicationFast(24, 89), 2136)
+	AssertOrPanicInt(MustInt(arithmetic.LongDivision(89, 24)), 3)
+	AssertOrPanicInt(MustInt(arithmetic.Permutation(8, 4)), 1680)
+	AssertOrPanicInt(MustInt(arithmetic.Combination(8, 4)), 70)
+	AssertOrPanic(Must(arithmetic.Factorial(9)), 362880)
+	AssertOrPanic(arithmetic.Pi(500000), math.Pi)
+	AssertOrPanicInt(arithmetic.Abs(-1), 1)
+	AssertOrPanic(arithmetic.AbsFloat(-1.0), 1.0)
+	AssertOrPanicInt(MustInt(arithmetic.ToThePowerInt(2, 5)), 32)
+	AssertOrPanic(arithmetic.ToThePowerFloat(2, 5), 32)
+	AssertOrPanic(Must(arithmetic.NewtonianSquareRoot(9, 0.00001)), 3)
+	AssertOrPanic(Must(arithmetic.HeronsSquareRoot(9, 0.00001)), 3)
+	AssertOrPanic(arithmetic.Exponent(1, 15), math.E)
+	AssertLogIsClose(Must(arithmetic.NaturalLog(2.5, 15)), 0.91629073187)
+	AssertLogIsClose(Must(arithmetic.LogBaseTen(2.5, 15)), 0.39794000867)
+	AssertOrPanic(Must(arithmetic.NaturalLog(2, 15)), math.Ln2)
+	AssertOrPanic(Must(arithmetic.NaturalLog(10, 15)), math.Ln10)
+	AssertOrPanic(Must(arithmetic.NaturalLog(1e-300, 15)), -300*math.Ln10)
+	AssertOrPanic(Must(arithmetic.LogBaseTen(100, 15)), 2)
+
+	_, err := arithmetic.LongDivision(1, 0)
+	AssertError(err, arithmetic.ErrDivisionByZero)
+	_, err = arithmetic.Permutation(4, 8)
+	AssertError(err, arithmetic.ErrKOutOfRange)
+	_, err = arithmetic.Combination(4, -1)
+	AssertError(err, arithmetic.ErrKOutOfRange)
+	_, err = arithmetic.Factorial(0.5)
+	AssertError(err, arithmetic.ErrNotWholeNumber)
+	_, err = arithmetic.Factorial(-3)
+	AssertError(err, arithmetic.ErrNotWholeNumber)
+	_, err = arithmetic.BigFactorial(-3)
+	AssertError(err, arithmetic.ErrNotWholeNumber)
+	_, err = arithmetic.HeronsSquareRoot(-1, 0.00001)
+	AssertError(err, arithmetic.ErrSqrtDomain)
+	_, err = arithmetic.NewtonianSquareRoot(2, 0)
+	AssertError(err, arithmetic.ErrMarginOfError)
+	_, err = arithmetic.NaturalLog(0, 15)
+	AssertError(err, arithmetic.ErrLogDomain)
 
 	PrintAllTestsOk()
 }
@@ -52,12 +109,17 @@ func TestTrigonometryFunctions() {
 	x := 0.25
 	accuracy := 9
 
-	AssertOrPanic(math.Sin(x), Sine(x, accuracy))
-	AssertOrPanic(math.Asin(x), InverseSine(x, accuracy))
-	AssertOrPanic(math.Tan(x), Tangent(x, accuracy))
-	AssertOrPanic(math.Atan(x), InverseTangent(x, accuracy))
-	AssertOrPanic(math.Cos(x), Cosine(x, accuracy))
-	AssertOrPanic(math.Acos(x), InverseCosine(x, accuracy))
+	AssertOrPanic(math.Sin(x), trig.Sine(x, accuracy))
+	AssertOrPanic(math.Asin(x), Must(trig.InverseSine(x, accuracy)))
+	AssertOrPanic(math.Tan(x), trig.Tangent(x, accuracy))
+	AssertOrPanic(math.Atan(x), trig.InverseTangent(x, accuracy))
+	AssertOrPanic(math.Cos(x), trig.Cosine(x, accuracy))
+	AssertOrPanic(math.Acos(x), Must(trig.InverseCosine(x, accuracy)))
+
+	_, err := trig.InverseSine(2, accuracy)
+	AssertError(err, trig.ErrDomain)
+	_, err = trig.InverseCosine(-1.5, accuracy)
+	AssertError(err, trig.ErrDomain)
 
 	PrintAllTestsOk()
 }
@@ -69,24 +131,1222 @@ func TestStatsFunctions() {
 
 	data := []float64{1, 4, 3, 5, 2, 6, 4}
 
-	AssertOrPanic(Min(data), 1)
-	AssertOrPanic(Max(data), 6)
-	AssertOrPanic(Mean(data), 3.571428571)
-	AssertOrPanic(StandardDeviation(data), 1.5907898179514)
-	AssertOrPanic(Median(data), 4)
-	AssertOrPanic(Sum(data), 25)
-	AssertOrPanic(Mode(data), 4)
-	AssertOrPanic(NormalDistributionPdf(data, 2.5), 0.19989228)
-
-	// Shuffling the data before testing sort. This is because sort has already
-	// been called previously by the Median function.
-	data = []float64{1, 4, 3, 5, 2, 6, 4}
-	QuickSort(data)
+	AssertOrPanic(Must(stats.Min(data)), 1)
+	AssertOrPanic(Must(stats.Max(data)), 6)
+	AssertOrPanic(Must(stats.Mean(data)), 3.571428571)
+	AssertOrPanic(Must(stats.StandardDeviation(data)), 1.5907898179514)
+	AssertOrPanic(Must(stats.Median(data)), 4)
+	AssertOrPanic(stats.Sum(data), 25)
+	AssertOrPanic(Must(stats.Mode(data)), 4)
+	AssertOrPanic(Must(stats.NormalDistributionPdf(data, 2.5)), 0.19989228)
+
+	// Median sorts a copy of the data, so it is still shuffled here.
+	stats.QuickSort(data)
 	AssertSortWorked(data)
 
+	_, err := stats.Mean(nil)
+	AssertError(err, stats.ErrEmptyData)
+	_, err = stats.Mode([]float64{})
+	AssertError(err, stats.ErrEmptyData)
+	_, err = stats.NormalDistributionPdf([]float64{2, 2}, 2)
+	AssertError(err, stats.ErrZeroDeviation)
+
+	PrintAllTestsOk()
+}
+
+// TestExpressionEvaluator checks precedence, associativity and function calls
+// in the infix expression evaluator.
+func TestExpressionEvaluator() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Expression Tests ...                                |")
+
+	AssertExpression("1 + 2 * 3", 7)
+	AssertExpression("(1 + 2) * 3", 9)
+	AssertExpression("2^3^2", 512)
+	AssertExpression("-2^2", -4)
+	AssertExpression("89 / 24", 3)
+	AssertExpression("89.0 / 24", 89.0/24)
+	AssertExpression("5! - p(8, 4)", 120-1680)
+	AssertExpression("3 + 4 * sin(0.25) - 2^5 / 7", 3+4*math.Sin(0.25)-4)
+	AssertExpression("sqrt(9) + cos(0.25, 9)", 3+math.Cos(0.25))
+	AssertExpression("median([1, 4, 3, 5, 2, 6, 4])", 4)
+	AssertExpression("pdf([1, 4, 3, 5, 2, 6, 4], 2.5)", 0.19989228)
+	AssertExpression("2 * pi", 2*math.Pi)
+	AssertExpression("2^70", math.Pow(2, 70))
+	AssertExpression("100000000000 * 100000000000", 1e22)
+	AssertExpression("multiply(100000000000, 100000000000)", 1e22)
+	AssertExpression("-9223372036854775807 - 2", -9223372036854775809.0)
+	AssertDisplay("sqrt(2)", "1.41421")
+	AssertExpression("sqrt(1e20)", 1e10)
+	AssertExpressionFails("1 / 0")
+	AssertExpression("ln(2)", math.Ln2)
+	AssertExpression("ln(10)", math.Ln10)
+	AssertExpression("log(100)", 2)
+	AssertExpressionFails("ln(0)")
+	AssertExpressionFails("sin(1, 2, 3)")
+	AssertExpressionFails("(1 + 2")
+	AssertExpressionFails("pi = 3")
+
+	AssertExpressionFails("sin(x) = x")
+	AssertExpressionFails("f(x, x) = x")
+
+	session := NewSession()
+	for _, statement := range []string{"x = 2 * 3", "f(x, y) = sqrt(x^2 + y^2)", "g(t) = f(t, x) + 1"} {
+		if _, err := EvaluateExpression(statement, session.Scope); err != nil {
+			panic(err)
+		}
+	}
+	AssertOrPanic(session.Scope.Variables["x"].AsFloat(), 6)
+	if v, err := EvaluateExpression("g(8)", session.Scope); err != nil || math.Abs(v.AsFloat()-11) > 0.0001 {
+		panic("User function did not match expected output.")
+	}
+	if _, err := EvaluateExpression("f(1)", session.Scope); err == nil {
+		panic("User function arity was not checked.")
+	}
+	session.Scope.Variables["data"] = ListValue([]float64{1, 2, 3})
+	if session.ExpandInput("x") != "6" || session.ExpandInput("data") != "1,2,3" {
+		panic("Variables were not expanded.")
+	}
+
+	session.SetAnswer(IntValue(5))
+	session.MemoryAdd(DefaultMemorySlot, 1)
+	session.MemoryAdd(DefaultMemorySlot, 1)
+	session.MemoryAdd("a", -1)
+	if v, _ := EvaluateExpression("mr - mr(a)", session.Scope); v.AsFloat() != 15 {
+		panic("Memory registers did not match expected output.")
+	}
+	if session.ExpandInput("mr a") != "-5" {
+		panic("Memory registers were not expanded.")
+	}
+	for _, name := range []string{"5", "2x", "a+b"} {
+		if err := ExecuteMemoryCommand("m+", []string{name}); err == nil {
+			panic("Memory register name should have been rejected: " + name)
+		}
+	}
+
+	history := &History{entries: []string{"pdf", "sd", "x = 2"}}
+	if line, _ := history.Expand("!!"); line != "x = 2" {
+		panic("!! did not expand to the last line.")
+	}
+	if line, _ := history.Expand("!2"); line != "sd" {
+		panic("!n did not expand to the n-th line.")
+	}
+	if _, err := history.Expand("!4"); err == nil {
+		panic("!n past the end of the history should fail.")
+	}
+
 	PrintAllTestsOk()
 }
 
+// TestRPN checks the stack operations of RPN mode.
+func TestRPN() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running RPN Tests ...                                       |")
+
+	AssertRPN("3 4 + 2 *", 14)
+	AssertRPN("3 4 -", -1)
+	AssertRPN("89 24 /", 3)
+	AssertRPN("0.25 sin", math.Sin(0.25))
+	AssertRPN("2 3 swap ^", 9)
+	AssertRPN("5 dup *", 25)
+	AssertRPN("1 2 3 roll drop +", 4)
+	AssertRPN("8 4 p", 1680)
+	AssertRPN("5 !", 120)
+	AssertRPN("1 2 3 4 4 mean", 2.5)
+	AssertRPN("10 1 2 3 3 sum drop", 10)
+	AssertRPNFails("1 2 mean")
+	AssertRPNFails("1 2 0 mean")
+	AssertRPNFails("1 2 1.5 sum")
+
+	// Memory commands work on the top of the stack, which is the answer.
+	session := CurrentSession
+	CurrentSession = NewSession()
+	defer func() { CurrentSession = session }()
+	CurrentSession.RPN = true
+	CurrentSession.Scope.Variables["x"] = IntValue(1)
+	for _, line := range []string{"3 4 +", "m+", "m+ a", "2", "m-", "del x"} {
+		if err := CaptureRPNLine(line); err != nil {
+			panic(fmt.Sprintf("%s in RPN mode gave %s", line, err))
+		}
+	}
+	if CurrentSession.MemoryRecall(DefaultMemorySlot).AsFloat() != 5 || CurrentSession.MemoryRecall("a").AsFloat() != 7 {
+		panic("Memory commands did not work in RPN mode.")
+	}
+	if _, ok := CurrentSession.Scope.Variables["x"]; ok {
+		panic("del did not work in RPN mode.")
+	}
+	if err := CaptureRPNLine("mr 2 *"); err != nil || CurrentSession.Stack[len(CurrentSession.Stack)-1].AsFloat() != 10 {
+		panic("mr did not push the register in RPN mode.")
+	}
+	if err := CaptureRPNLine("mc"); err != nil || len(CurrentSession.Scope.Memory) != 1 {
+		panic("mc did not clear the register in RPN mode.")
+	}
+
+	PrintAllTestsOk()
+}
+
+// TestAngleModes checks angle unit suffixes, conversions and the angle mode of
+// the session.
+func TestAngleModes() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Angle Tests ...                                     |")
+
+	AssertExpression("sin(30deg)", 0.5)
+	AssertExpression("cos(200grad)", -1)
+	AssertExpression("arcsin(0.5) -> deg", 30)
+	AssertExpression("100grad -> deg", 90)
+	AssertExpression("180deg", math.Pi)
+	AssertExpressionFails("1 -> sin")
+
+	unit := CurrentSession.Angle
+	CurrentSession.Angle = Degrees
+	AssertExpression("sin(30)", 0.5)
+	AssertExpression("arctan(0.5)", math.Atan(0.5)*180/math.Pi)
+	AssertExpression("sin(pi rad)", 0)
+	AssertFunction("arccos", 60, FloatValue(0.5))
+	CurrentSession.Angle = Gradians
+	AssertFunction("sin", math.Sqrt(2)/2, IntValue(50), IntValue(15))
+	CurrentSession.Angle = unit
+
+	PrintAllTestsOk()
+}
+
+// TestNumberFormats checks every number format style.
+func TestNumberFormats() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Format Tests ...                                    |")
+
+	AssertFormat("fix 3", math.Pi, "3.142")
+	AssertFormat("fix 0", 2.5e6, "2500000")
+	AssertFormat("sig 4", 0.0000123456, "0.00001235")
+	AssertFormat("sig 3", 123456, "123000")
+	AssertFormat("sig 3", 9.996, "10.0")
+	AssertFormat("sci 3", 0.0000123456, "1.235e-05")
+	AssertFormat("eng 2", 0.0000123456, "12.35e-06")
+	AssertFormat("eng 3", 999999.9, "1.000e+06")
+	AssertFormat("eng 1", -4700, "-4.7e+03")
+	AssertFormat("auto 10", 1.0/3, "0.3333333333")
+	AssertFormat("auto 10", 5.18470552858707e21, "5.184705529e+21")
+
+	for _, fields := range []string{"", "bogus", "fix -1", "sig 0", "sci 18", "fix x"} {
+		if _, err := ParseNumberFormat(strings.Fields(fields)); err == nil {
+			panic("Format should have been rejected: " + fields)
+		}
+	}
+
+	PrintAllTestsOk()
+}
+
+// TestProgrammerMode checks integer literals in other bases, the bitwise
+// operations and the display of integers in other bases.
+func TestProgrammerMode() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Programmer Tests ...                                |")
+
+	AssertExpression("0xff", 255)
+	AssertExpression("0b1010 + 0o17", 25)
+	AssertExpression("-0x10", -16)
+	AssertExpression("010", 10)
+	AssertExpression("0xff & 0b1010", 10)
+	AssertExpression("1 << 4 | 1", 17)
+	AssertExpression("1 + 1 << 2", 8)
+	AssertExpression("~0", -1)
+	AssertExpression("-16 >> 2", -4)
+	AssertExpression("xor(0xf0, 0xff)", 15)
+	AssertExpression("rol(1, 65)", 2)
+	AssertExpression("ror(1, 1)", math.MinInt64)
+	AssertExpressionFails("1.5 & 1")
+	AssertExpressionFails("1 << -1")
+	AssertExpressionFails("0x1g")
+
+	AssertFunction("shl", 12, IntValue(3), IntValue(2))
+	AssertFunction("not", -6, IntValue(5))
+	x, err := ParseIntLiteral("-0b101")
+	if err != nil || x != -5 {
+		panic("Function did not match expected output.")
+	}
+
+	for base, expected := range map[NumberBase]string{
+		DecimalBase: "-255",
+		HexBase:     "-0xff",
+		OctalBase:   "-0o377",
+		BinaryBase:  "-0b11111111",
+		AllBases:    "-255 (-0xff, -0o377, -0b11111111)",
+	} {
+		if s := FormatIntegerInBase(-255, base); s != expected {
+			panic(fmt.Sprintf("%s of -255 gave %s, expected %s", base, s, expected))
+		}
+	}
+
+	PrintAllTestsOk()
+}
+
+// TestWordSizes checks that integers wrap to the word size and that the carry
+// and overflow flags are raised.
+func TestWordSizes() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Word Size Tests ...                                 |")
+
+	word := CurrentSession.Word
+	AssertWord("int8", "127 + 1", -128, WordFlags{Overflow: true})
+	AssertWord("int8", "-128", -128, WordFlags{})
+	AssertWord("int8", "-128 - 1", 127, WordFlags{Overflow: true})
+	AssertWord("int8", "1 - 2", -1, WordFlags{Carry: true})
+	AssertWord("int8", "-128 / -1", -128, WordFlags{Overflow: true})
+	AssertWord("int8", "0xff", -1, WordFlags{})
+	AssertWord("uint8", "255 + 1", 0, WordFlags{Carry: true})
+	AssertWord("uint8", "0 - 1", 255, WordFlags{Carry: true})
+	AssertWord("uint8", "-1", 255, WordFlags{Carry: true})
+	AssertWord("uint8", "not(0)", 255, WordFlags{})
+	AssertWord("uint8", "rol(0x81, 1)", 3, WordFlags{})
+	AssertWord("uint8", "ror(0x81, 1)", 0xc0, WordFlags{})
+	AssertWord("uint8", "1 << 8", 0, WordFlags{Carry: true, Overflow: true})
+	AssertWord("int16", "0x7fff * 2", -2, WordFlags{Overflow: true})
+	AssertWord("int16", "2^15", -32768, WordFlags{Overflow: true})
+	AssertWord("int16", "8!", -25216, WordFlags{Overflow: true})
+	AssertWord("int16", "9!", -30336, WordFlags{Carry: true, Overflow: true})
+	AssertWord("uint8", "50000000!", 0, WordFlags{Carry: true, Overflow: true})
+	AssertWord("uint32", "0xffffffff >> 4", 0x0fffffff, WordFlags{})
+	AssertWord("uint64", "(0 - 1) / 2", math.MaxInt64, WordFlags{Carry: true})
+	AssertWord("int64", "0x7fffffffffffffff + 1", math.MinInt64, WordFlags{Overflow: true})
+
+	CurrentSession.Word, _ = ParseWordSize("int8")
+	if s := CurrentSession.Word.BitPattern(-128); s != "1000 0000" {
+		panic("unexpected bit pattern " + s)
+	}
+	if s := CurrentSession.Word.FormatInBase(-1, HexBase); s != "0xff" {
+		panic("unexpected hex pattern " + s)
+	}
+	for _, name := range []string{"int7", "uint", "float32", "int128"} {
+		if _, err := ParseWordSize(name); err == nil {
+			panic("Word size should have been rejected: " + name)
+		}
+	}
+	CurrentSession.Word = word
+	CurrentSession.Flags = WordFlags{}
+
+	PrintAllTestsOk()
+}
+
+// AssertWord evaluates an expression in the named word size and compares the
+// wrapped result and the flags raised. Signed results are compared as they
+// are displayed.
+func AssertWord(word, input string, expected int, flags WordFlags) {
+	CurrentSession.Word, _ = ParseWordSize(word)
+	CurrentSession.Flags = WordFlags{}
+	v, err := EvaluateExpression(input, NewScope())
+	if err != nil {
+		panic(err)
+	}
+	x := CurrentSession.Word.Wrap(v.Int)
+	if v.Kind != IntKind || x != expected || CurrentSession.Flags != flags {
+		panic(fmt.Sprintf("%s in %s gave %d [%s], expected %d [%s]", input, word, x,
+			CurrentSession.Flags, expected, flags))
+	}
+}
+
+// TestFloatInspection checks the IEEE-754 fields of a few special values.
+func TestFloatInspection() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running IEEE-754 Tests ...                                  |")
+
+	f := InspectFloat(0.1)
+	if f.Bits != 0x3fb999999999999a || f.Sign != 0 || f.Exponent != 1019 || f.Class != "normal" {
+		panic("Function did not match expected output.")
+	}
+	AssertOrPanicInt(f.UnbiasedExponent(), -4)
+	AssertOrPanic(f.ULP, math.Pow(2, -56))
+
+	f = InspectFloat(-2)
+	if f.Sign != 1 || f.Mantissa != 0 || f.UnbiasedExponent() != 1 || f.ULP != math.Pow(2, -51) {
+		panic("Function did not match expected output.")
+	}
+
+	f = InspectFloat(math.SmallestNonzeroFloat64)
+	if f.Class != "subnormal" || f.Mantissa != 1 || f.Previous != 0 || f.UnbiasedExponent() != -1022 {
+		panic("Function did not match expected output.")
+	}
+
+	f = InspectFloat(math.MaxFloat64)
+	if f.Class != "normal" || !math.IsInf(f.Next, 1) || f.ULP != math.Pow(2, 971) {
+		panic("Function did not match expected output.")
+	}
+
+	for x, class := range map[float64]string{0: "zero", math.Inf(-1): "infinity", math.NaN(): "NaN"} {
+		if f := InspectFloat(x); f.Class != class {
+			panic("Expected class " + class + ", got " + f.Class)
+		}
+	}
+
+	PrintAllTestsOk()
+}
+
+// TestJSONOutput checks the JSON objects printed for results.
+func TestJSONOutput() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running JSON Output Tests ...                               |")
+
+	r := ResultRecord{Function: "sin", Inputs: jsonFields([]string{"x", "0.25"}),
+		Params: jsonFields([]string{"n", "9"})}
+	r.SetResult(FloatValue(0.5))
+	AssertJSON(r, `{"function":"sin","inputs":{"x":0.25},"params":{"n":9},"result":0.5,"display":"0.50000"}`)
+
+	r = ResultRecord{Function: "mean", Inputs: jsonFields([]string{"data", "1, 2,3"})}
+	r.SetResult(IntValue(2))
+	AssertJSON(r, `{"function":"mean","inputs":{"data":[1,2,3]},"result":2,"display":"2"}`)
+
+	r = ResultRecord{Expression: "1/0", Error: ErrDivisionByZero.Error()}
+	AssertJSON(r, `{"expression":"1/0","error":"division by zero"}`)
+	AssertJSON(ErrorRecord(" 1/0\n", ErrDivisionByZero), `{"expression":"1/0","error":"division by zero"}`)
+
+	var err error
+	divide, _ := LookupFunction("divide")
+	CaptureOutput(func() {
+		err = PromptFunctionAndCompute(divide, bufio.NewReader(strings.NewReader("1\n0\n")))
+	})
+	AssertJSON(ErrorRecord("divide", err), `{"function":"divide","inputs":{"x":1,"y":0},"error":"division by zero"}`)
+
+	r = ResultRecord{Expression: "x"}
+	r.SetResult(ListValue([]float64{1, math.Inf(1)}))
+	AssertJSON(r, `{"expression":"x","result":[1,"+Inf"],"display":"[1.00000, +Inf]"}`)
+	format := CurrentSession.Format
+	CurrentSession.Format, _ = ParseNumberFormat([]string{"fix", "2"})
+	if s := ListValue([]float64{1.5, 1e6}).String(); s != "[1.50, 1000000.00]" {
+		panic("list elements should follow the number format, got " + s)
+	}
+	CurrentSession.Format = format
+
+	word, flags := CurrentSession.Word, CurrentSession.Flags
+	CurrentSession.Word, _ = ParseWordSize("uint8")
+	CurrentSession.Flags = WordFlags{Carry: true}
+	r = ResultRecord{Expression: "0 - 1"}
+	r.SetResult(IntValue(-1))
+	AssertJSON(r, `{"expression":"0 - 1","result":255,"display":"255","word":"uint8","bits":"1111 1111","flags":["carry"]}`)
+	CurrentSession.Word, CurrentSession.Flags = word, flags
+
+	r = ResultRecord{Expression: "1", Inspection: InspectFloat(1).JSON()}
+	AssertJSON(r, `{"expression":"1","inspection":{"class":"normal","exponent":0,"hex":"0x3ff0000000000000","mantissa":"0x0000000000000","next":1.0000000000000002,"previous":0.9999999999999999,"sign":0,"ulp":2.220446049250313e-16}}`)
+
+	if _, err := ParseOutputMode("xml"); err == nil {
+		panic("Output mode should have been rejected: xml")
+	}
+
+	PrintAllTestsOk()
+}
+
+// TestAPIServer sends requests to the handler of calculator serve.
+func TestAPIServer() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running API Server Tests ...                                |")
+
+	AssertAPI("POST", "/v1/trig/sin", `{"x":0.25,"n":9}`, http.StatusOK,
+		`{"function":"sin","inputs":{"n":9,"x":0.25},"result":0.24740395925452294,"display":"0.24740"}`)
+	AssertAPI("POST", "/v1/stats/median", `{"data":[3,1,2]}`, http.StatusOK,
+		`{"function":"median","inputs":{"data":[3,1,2]},"result":2,"display":"2.00000"}`)
+	AssertAPI("POST", "/v1/arithmetic/add", `{"x":"0xff","y":1}`, http.StatusOK,
+		`{"function":"add","inputs":{"x":"0xff","y":1},"result":256,"display":"256"}`)
+	AssertAPI("POST", "/v1/eval", `{"expression":"2^3"}`, http.StatusOK,
+		`{"expression":"2^3","result":8,"display":"8"}`)
+	AssertAPI("POST", "/v1/arithmetic/divide", `{"x":1,"y":0}`, http.StatusUnprocessableEntity,
+		`{"function":"divide","inputs":{"x":1,"y":0},"error":"division by zero"}`)
+	AssertAPI("POST", "/v1/trig/sin", `{"x":1,"n":5000}`, http.StatusBadRequest,
+		`{"function":"sin","inputs":{"n":5000,"x":1},"error":"n must be between 0 and 1000"}`)
+	AssertAPI("POST", "/v1/eval", `{"expression":"sin(1, 5000)"}`, http.StatusBadRequest,
+		`{"expression":"sin(1, 5000)","error":"n must be between 0 and 1000"}`)
+	AssertAPI("POST", "/v1/arithmetic/add", `{"x":1}`, http.StatusBadRequest,
+		`{"function":"add","inputs":{"x":1},"error":"missing parameter y"}`)
+	AssertAPI("POST", "/v1/trig/cos", `{"x":0,"angle":"turns"}`, http.StatusBadRequest,
+		`{"function":"cos","inputs":{"angle":"turns","x":0},"error":"angle must be deg, rad or grad"}`)
+	AssertAPI("POST", "/v1/trig/median", `{"data":[1]}`, http.StatusNotFound,
+		`{"error":"no function at /v1/trig/median"}`)
+	AssertAPI("GET", "/v1/trig/sin", "", http.StatusMethodNotAllowed,
+		`{"function":"sin","error":"use POST"}`)
+
+	// A computation that times out must not hold up the requests after it.
+	timeout := RequestTimeout
+	RequestTimeout = 50 * time.Millisecond
+	blocked := make(chan struct{})
+	_, err := computeWithTimeout(func() (Value, error) { <-blocked; return Value{}, nil })
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable {
+		panic(fmt.Sprintf("a blocked computation gave %v, expected status 503", err))
+	}
+	if v, err := computeWithTimeout(func() (Value, error) { return IntValue(1), nil }); err != nil || v.Int != 1 {
+		panic(fmt.Sprintf("the request after a timeout gave %v, %v", v, err))
+	}
+	close(blocked)
+	RequestTimeout = timeout
+
+	PrintAllTestsOk()
+}
+
+// TestRPC sends JSON-RPC messages to a fresh session.
+func TestRPC() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running JSON-RPC Tests ...                                  |")
+
+	session := CurrentSession
+	CurrentSession = NewSession()
+	defer func() { CurrentSession = session }()
+
+	AssertRPC(`{"jsonrpc":"2.0","id":1,"method":"trig.sin","params":[0.25,9]}`,
+		`{"jsonrpc":"2.0","result":{"function":"sin","inputs":{"n":9,"x":0.25},"result":0.24740395925452294,"display":"0.24740"},"id":1}`)
+	AssertRPC(`{"jsonrpc":"2.0","method":"eval","params":{"expression":"x = 2^3"}}`, "")
+	AssertRPC(`{"jsonrpc":"2.0","id":"a","method":"eval","params":["x + 1"]}`,
+		`{"jsonrpc":"2.0","result":{"expression":"x + 1","result":9,"display":"9"},"id":"a"}`)
+	AssertRPC(`[{"jsonrpc":"2.0","id":2,"method":"vars.set","params":{"name":"y","value":[1,2]}},`+
+		`{"jsonrpc":"2.0","method":"vars.delete","params":["x"]},{"jsonrpc":"2.0","id":3,"method":"vars.list"}]`,
+		`[{"jsonrpc":"2.0","result":{"expression":"y","result":[1,2],"display":"[1.00000, 2.00000]"},"id":2},`+
+			`{"jsonrpc":"2.0","result":{"ans":9,"y":[1,2]},"id":3}]`)
+	AssertRPC(`{"jsonrpc":"2.0","id":4,"method":"stats.mean","params":{"data":"y"}}`,
+		`{"jsonrpc":"2.0","result":{"function":"mean","inputs":{"data":"y"},"result":1.5,"display":"1.50000"},"id":4}`)
+	AssertRPC(`{"jsonrpc":"2.0","id":5,"method":"arithmetic.divide","params":[1,0]}`,
+		`{"jsonrpc":"2.0","error":{"code":-32000,"message":"division by zero"},"id":5}`)
+	AssertRPC(`{"jsonrpc":"2.0","id":6,"method":"arithmetic.divide","params":[1]}`,
+		`{"jsonrpc":"2.0","error":{"code":-32602,"message":"missing parameter y"},"id":6}`)
+	AssertRPC(`{"jsonrpc":"2.0","id":7,"method":"stats.sin"}`,
+		`{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found: stats.sin"},"id":7}`)
+	AssertRPC(`{"jsonrpc":"2.0","id":`, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error"},"id":null}`)
+	AssertRPC(`[]`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: empty batch"},"id":null}`)
+
+	PrintAllTestsOk()
+}
+
+// AssertRPC handles a JSON-RPC message and compares the response. An empty
+// expected response means that there should be none.
+func AssertRPC(message, expected string) {
+	if got := string(HandleRPCMessage([]byte(message))); got != expected {
+		panic(fmt.Sprintf("%s gave %s, expected %s", message, got, expected))
+	}
+}
+
+// AssertAPI sends a request to the API handler and compares the status and
+// body of the response.
+func AssertAPI(method, path, body string, status int, expected string) {
+	recorder := httptest.NewRecorder()
+	NewAPIHandler().ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	got := strings.TrimSpace(recorder.Body.String())
+	if recorder.Code != status || got != expected {
+		panic(fmt.Sprintf("%s %s gave %d %s, expected %d %s", method, path, recorder.Code, got, status, expected))
+	}
+}
+
+// AssertJSON encodes a record and compares it to the expected JSON.
+func AssertJSON(r ResultRecord, expected string) {
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		panic(err)
+	}
+	if string(encoded) != expected {
+		panic(fmt.Sprintf("got %s, expected %s", encoded, expected))
+	}
+}
+
+// AssertFormat formats x in the given format and compares it to expected.
+func AssertFormat(format string, x float64, expected string) {
+	f, err := ParseNumberFormat(strings.Fields(format))
+	if err != nil {
+		panic(err)
+	}
+	if s := f.Format(x); s != expected {
+		panic(fmt.Sprintf("format %s of %g gave %s, expected %s", format, x, s, expected))
+	}
+}
+
+// AssertRPN runs a line in RPN mode and compares the top of the stack to the
+// expected value.
+func AssertRPN(input string, expected float64) {
+	stack := RPNStack{}
+	scope := NewScope()
+	for _, word := range strings.Fields(input) {
+		var err error
+		if stack, err = ExecuteRPNWord(stack, word, scope); err != nil {
+			panic(err)
+		}
+	}
+	AssertOrPanic(stack[len(stack)-1].AsFloat(), expected)
+}
+
+// CaptureRPNLine executes a line at the prompt and discards what it prints.
+func CaptureRPNLine(line string) error {
+	var err error
+	CaptureOutput(func() { err = ParseAndExecute(line, nil) })
+	return err
+}
+
+// AssertRPNFails ensures that one of the words of an RPN line fails.
+func AssertRPNFails(input string) {
+	stack := RPNStack{}
+	scope := NewScope()
+	for _, word := range strings.Fields(input) {
+		var err error
+		if stack, err = ExecuteRPNWord(stack, word, scope); err != nil {
+			return
+		}
+	}
+	panic("RPN line should have failed: " + input)
+}
+
+// TestFunctionRegistry checks that every name resolves to a single function
+// and that the prompts, help and command line all see the same functions.
+func TestFunctionRegistry() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Function Registry Tests ...                         |")
+
+	for _, alias := range []string{"c", "combination"} {
+		f, ok := LookupFunction(alias)
+		if !ok || f.Name != "combination" {
+			panic("Function not registered: " + alias)
+		}
+		v, err := f.Invoke([]Value{IntValue(5), IntValue(2)})
+		if err != nil {
+			panic(err)
+		}
+		AssertOrPanicInt(v.Int, 10)
+	}
+	AssertFunction("p", 20, IntValue(5), IntValue(2))
+	AssertFunction("sqrt", 3, IntValue(9))
+	AssertFunction("mean", 2, IntValue(1), IntValue(2), IntValue(3))
+	_, err := CallBuiltin("c", []Value{IntValue(2), IntValue(5)})
+	AssertError(err, arithmetic.ErrKOutOfRange)
+	AssertExpressionFails("shl(1, -1)")
+	AssertExpressionFails("factorial(2.5)")
+	AssertExpressionFails("factorial(-3)")
+	AssertExpressionFails("(-3)!")
+	AssertExpressionFails("sin()")
+	AssertExpressionFails(fmt.Sprintf("sin(1, %d)", MaxTerms+1))
+	AssertExpressionFails(fmt.Sprintf("pow(2, %d)", MaxExponent+1))
+	AssertExpression("0.5^1e15", 0)
+	if v, err := EvaluateExpression("factorial(50000000)", NewScope()); err != nil || !math.IsInf(v.AsFloat(), 1) {
+		panic(fmt.Sprintf("factorial(50000000) gave %v %v, expected +Inf", v, err))
+	}
+
+	for _, f := range FunctionRegistry {
+		if got, ok := LookupFunction(f.Name); !ok || got != f {
+			panic("Function not registered: " + f.Name)
+		}
+		if _, ok := APIFunction(f.Category, f.Name); !ok {
+			panic("Function not served: " + f.Name)
+		}
+		for _, p := range f.OptionalParams() {
+			if !p.Optional || len(p.Flags) == 0 {
+				panic("Optional parameter without a flag in " + f.Name)
+			}
+		}
+	}
+
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	addSettingFlags(fs)
+	for input, expected := range map[string]string{
+		"--word int8 -128/-1":    "-128/-1",
+		"--json -- --1":          "--1",
+		"--base=hex --bigint -1": "-1",
+	} {
+		args, err := parseLeadingFlags(fs, strings.Fields(input))
+		if err != nil || strings.Join(args, " ") != expected {
+			panic(fmt.Sprintf("%s: expected %s, got %v", input, expected, args))
+		}
+	}
+	if moved := moveLeadingFlags([]string{"--format", "sci", "--json", "sin", "0.25"}); strings.Join(moved, " ") != "sin --format sci --json 0.25" {
+		panic(fmt.Sprintf("flags not moved behind the command: %v", moved))
+	}
+
+	PrintAllTestsOk()
+}
+
+// TestCommandLine runs one-shot commands and checks what they print and their
+// exit status.
+func TestCommandLine() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Command-Line Tests ...                              |")
+
+	session := CurrentSession
+	defer func() { CurrentSession = session }()
+
+	AssertCommandLine([]string{"sqrt", "4"}, ExitOk, "2.00000")
+	AssertCommandLine([]string{"--json", "sqrt", "4"}, ExitOk,
+		`{"function":"sqrt","inputs":{"x":4},"params":{"margin":1e-12},"result":2,"display":"2.00000"}`)
+
+	// Optional parameters are flags, before or after the positional arguments,
+	// and are left at their defaults when not given.
+	AssertCommandLine([]string{"--json", "sin", "0.25"}, ExitOk,
+		`{"function":"sin","inputs":{"x":0.25},"params":{"n":15},"result":0.24740395925452294,"display":"0.24740"}`)
+	AssertCommandLine([]string{"--json", "sin", "0.25", "-n", "2"}, ExitOk,
+		`{"function":"sin","inputs":{"x":0.25},"params":{"n":2},"result":0.24740397135416667,"display":"0.24740"}`)
+	AssertCommandLine([]string{"sin", "--json", "--n", "2", "0.25"}, ExitOk,
+		`{"function":"sin","inputs":{"x":0.25},"params":{"n":2},"result":0.24740397135416667,"display":"0.24740"}`)
+	AssertCommandLine([]string{"sqrt", "2", "--margin", "0.1"}, ExitOk, "1.41667")
+	AssertCommandLine([]string{"--format", "fix", "--digits", "2", "sin", "0.25"}, ExitOk, "0.25")
+	AssertCommandLine([]string{"mean", "1", "2", "3"}, ExitOk, "2.00000")
+	AssertCommandLine([]string{"mean", "1,2,3"}, ExitOk, "2.00000")
+	AssertCommandLine([]string{"eval", "--base", "hex", "255"}, ExitOk, "0xff")
+	AssertCommandLine([]string{"eval", "--word", "uint8", "--", "-1"}, ExitOk, "255")
+	AssertCommandLine([]string{"--json", "divide", "1", "0"}, ExitFailure,
+		`{"function":"divide","inputs":{"x":1,"y":0},"error":"division by zero"}`)
+	AssertCommandLine([]string{"sin", "0.25", "--n", "abc"}, ExitUsageError, "")
+	AssertCommandLine([]string{"sin", "0.25", "--bogus", "2"}, ExitUsageError, "")
+	AssertCommandLine([]string{"add", "1"}, ExitUsageError, "")
+	AssertCommandLine([]string{"--word", "int7", "add", "1", "2"}, ExitUsageError, "")
+	AssertCommandLine([]string{"nosuch"}, ExitUsageError, "")
+
+	// Piped input exits at its end with status 1 if any line failed.
+	AssertPrompt("1 + 1\n", ExitOk)
+	AssertPrompt("", ExitOk)
+	AssertPrompt("1/0\n2\n", ExitFailure)
+	AssertPrompt("x = 2\nx + 1", ExitOk)
+	if x := CurrentSession.Scope.Variables[AnswerVariable]; x.AsFloat() != 3 {
+		panic("the last line should be executed even without a newline")
+	}
+
+	PrintAllTestsOk()
+}
+
+// TestScripts runs a script that fails on two of its lines, once stopping at
+// the first failure and once with --keep-going.
+func TestScripts() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Script Tests ...                                    |")
+
+	file, err := os.CreateTemp("", "calculator-*.calc")
+	if err != nil {
+		panic(err)
+	}
+	defer os.Remove(file.Name())
+	// The inputs of add are read from the two lines after it, so the lines
+	// are still counted right after a prompt.
+	file.WriteString("# comment\nx = 2\n\n1/0\nadd\nx\n3\nnosuch(\nz = ans * 2\n")
+	file.Close()
+	path := file.Name()
+
+	session := CurrentSession
+	defer func() { CurrentSession = session }()
+	CurrentSession = NewSession()
+	CaptureOutput(func() { err = RunScript(path, false) })
+	if err == nil || err.Error() != path+":4: division by zero" {
+		panic(fmt.Sprintf("script gave %v, expected it to stop at line 4", err))
+	}
+	if _, ok := CurrentSession.Scope.Variables["z"]; ok {
+		panic("script should have stopped at its first failure")
+	}
+
+	CurrentSession = NewSession()
+	CurrentSession.Output = JSONOutput
+	output := CaptureOutput(func() { err = RunScript(path, true) })
+	if err == nil || err.Error() != path+": 2 lines failed" {
+		panic(fmt.Sprintf("script gave %v, expected 2 lines to fail", err))
+	}
+	for _, line := range []string{
+		`{"expression":"1/0","error":"` + path + `:4: division by zero"}`,
+		`{"function":"add","inputs":{"x":2,"y":3},"result":5,"display":"5"}`,
+		`{"expression":"nosuch(","error":"` + path + `:8: unexpected end of expression"}`,
+	} {
+		if !strings.Contains(output, line+"\n") {
+			panic(fmt.Sprintf("script printed %s, expected %s", output, line))
+		}
+	}
+	if z := CurrentSession.Scope.Variables["z"]; z.AsFloat() != 10 {
+		panic("script should have kept going after its failures")
+	}
+	AssertCommandLine([]string{"run", "--keep-going", path}, ExitFailure,
+		"x = 2 = 2\n"+strings.Repeat("=", 63)+"\nadd(2, 3) = 5\n"+strings.Repeat("=", 63)+"\nz = ans * 2 = 10\n"+strings.Repeat("=", 63))
+
+	PrintAllTestsOk()
+}
+
+// TestLineEditor types keys into the line editor and checks the line it
+// returns.
+func TestLineEditor() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Line Editor Tests ...                               |")
+
+	AssertLineEditor("abc\r", "abc")
+	AssertLineEditor("abd\x7fc\r", "abc")
+	AssertLineEditor("bc\x01a\x05d\n", "abcd")
+	AssertLineEditor("ac\x02b\x06d\r", "abcd")
+	AssertLineEditor("ac\x1b[Db\x1b[Cd\r", "abcd")
+	AssertLineEditor("bc\x1b[Ha\x1b[Fd\r", "abcd")
+	AssertLineEditor("xabc\x01\x1b[3~\r", "abc")
+	AssertLineEditor("xabc\x01\x04\r", "abc")
+	AssertLineEditor("abc xyz\x17\r", "abc ")
+	AssertLineEditor("abcxyz\x02\x02\x02\x0b\r", "abc")
+	AssertLineEditor("xyzabc\x02\x02\x02\x15\r", "abc")
+	AssertLineEditor("abc\x03", "")
+
+	AssertLineEditor("\x1b[A\r", "2 + 2", "1 + 1", "2 + 2")
+	AssertLineEditor("\x1b[A\x1b[A\x1b[A\r", "1 + 1", "1 + 1", "2 + 2")
+	AssertLineEditor("draft\x1b[A\x1b[B\r", "draft", "1 + 1")
+	AssertLineEditor("\x1b[A*2\r", "1 + 1*2", "1 + 1")
+
+	AssertLineEditor("sq\t\r", "sqrt ")
+	AssertLineEditor("x = sq\t(2)\r", "x = sqrt (2)")
+	AssertLineEditor("s\t\r", "s")
+	AssertLineEditor("si\t\r", "sin")
+	AssertLineEditor("sin\t\th\t\r", "sinh ")
+	AssertLineEditor("zz\t\r", "zz")
+	if matches, _ := Complete("dig", CompletionCandidates()); len(matches) != 1 || matches[0] != "digits" {
+		panic(fmt.Sprintf("dig should complete to digits, got %v", matches))
+	}
+
+	editor := &LineEditor{reader: bufio.NewReader(strings.NewReader("\x04")), candidates: func() []string { return nil }}
+	var err error
+	CaptureOutput(func() { _, err = editor.Run() })
+	if err != io.EOF {
+		panic("Ctrl-D on an empty line should end the input")
+	}
+
+	PrintAllTestsOk()
+}
+
+// AssertLineEditor types keys into a line editor with the given history and
+// compares the line it returns. sqrt, sin and sinh can be completed.
+func AssertLineEditor(keys, expected string, history ...string) {
+	editor := &LineEditor{
+		reader:     bufio.NewReader(strings.NewReader(keys)),
+		history:    history,
+		index:      len(history),
+		candidates: func() []string { return []string{"sqrt", "sin", "sinh"} },
+	}
+	var line string
+	var err error
+	CaptureOutput(func() { line, err = editor.Run() })
+	if err != nil || line != expected {
+		panic(fmt.Sprintf("typing %q gave %q, %v, expected %q", keys, line, err, expected))
+	}
+}
+
+// AssertCommandLine runs a command in a fresh session and compares its exit
+// status and what it printed to stdout.
+func AssertCommandLine(args []string, status int, expected string) {
+	CurrentSession = NewSession()
+	var got int
+	output := CaptureOutput(func() { got = RunCommandLine(args) })
+	if got != status || strings.TrimSpace(output) != expected {
+		panic(fmt.Sprintf("%q gave %d %q, expected %d %q", args, got, output, status, expected))
+	}
+}
+
+// AssertPrompt feeds input to the main prompt of a fresh session until it ends
+// and compares the exit status.
+func AssertPrompt(input string, status int) {
+	CurrentSession = NewSession()
+	var got int
+	CaptureOutput(func() { got = RunPrompt(bufio.NewReader(strings.NewReader(input))) })
+	if got != status {
+		panic(fmt.Sprintf("%q gave exit status %d, expected %d", input, got, status))
+	}
+}
+
+// CaptureOutput runs f and returns what it printed to stdout. What it prints to
+// stderr is discarded.
+func CaptureOutput(f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		panic(err)
+	}
+	output := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		output <- string(b)
+	}()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, devNull
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		devNull.Close()
+	}()
+	f()
+	w.Close()
+	return <-output
+}
+
+// TestPlugins converts plugin functions to registry entries and checks that
+// names already in use are rejected.
+func TestPlugins() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Plugin Tests ...                                    |")
+
+	scale := plugins.Function{
+		Name: "scale",
+		Params: []plugins.Param{
+			{Name: "data", Kind: plugins.Data},
+			{Name: "factor", Kind: plugins.Int, Optional: true, Default: 2},
+		},
+		Call: func(args plugins.Args) (float64, error) {
+			return stats.Sum(args.Data("data")) * float64(args.Int("factor")), nil
+		},
+	}
+	f, err := PluginFunction(scale)
+	if err != nil {
+		panic(err)
+	}
+	AssertInvoke(f, 12, ListValue([]float64{1, 2, 3}))
+	AssertInvoke(f, 18, ListValue([]float64{1, 2, 3}), IntValue(3))
+	if _, err := f.Invoke([]Value{ListValue([]float64{1}), FloatValue(1.5)}); err == nil {
+		panic("Plugin function should have rejected a float factor")
+	}
+
+	for _, name := range []string{"sin", "c", "pi", "help", "Scale", "two words"} {
+		if err := RegisterFunction(&Function{Name: name}); err == nil {
+			panic("Function name should have been rejected: " + name)
+		}
+	}
+	AssertError(plugins.Validate(plugins.Function{Name: "f"}), plugins.ErrInvalidFunction)
+	scale.Params[1].Name = "json"
+	if _, err := PluginFunction(scale); err == nil {
+		panic("Optional parameter should not be allowed to shadow a flag")
+	}
+
+	description := externalFunction{Name: "calibrate", Params: []externalParam{{Name: "raw", Kind: "int"}}}
+	if p, err := externalPluginFunction("calibrate", description); err != nil || p.Params[0].Kind != plugins.Int {
+		panic("Plugin executable description was not understood")
+	}
+	description.Params[0].Kind = "complex"
+	if _, err := externalPluginFunction("calibrate", description); err == nil {
+		panic("Unknown parameter kind should have been rejected")
+	}
+
+	PrintAllTestsOk()
+}
+
+// TestBigIntegers checks the digit-array arithmetic of BigInt and the big
+// integer mode.
+func TestBigIntegers() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Big Integer Tests ...                               |")
+
+	x, err := arithmetic.ParseBigInt("-123456789012345678901234567890")
+	if err != nil {
+		panic(err)
+	}
+	y := arithmetic.NewBigInt(987654321)
+	long := arithmetic.BigLongMultiplication(x, y)
+	AssertBigInt(long, "-121932631124828532112482853211126352690")
+	AssertBigInt(arithmetic.BigKaratsubaMultiplication(x, y), long.String())
+	q, r, err := arithmetic.BigLongDivision(x, y)
+	if err != nil {
+		panic(err)
+	}
+	AssertBigInt(q, "-124999998873437499901")
+	AssertBigInt(r, "-574845669")
+	AssertBigInt(arithmetic.BigAdd(x, x.Neg()), "0")
+	AssertBigInt(arithmetic.BigSubtract(y, x), "123456789012345678902222222211")
+	if _, _, err := arithmetic.BigLongDivision(x, arithmetic.BigInt{}); !errors.Is(err, arithmetic.ErrDivisionByZero) {
+		panic("BigLongDivision should have failed with division by zero")
+	}
+	AssertError(func() error { _, err := arithmetic.ParseBigInt("12a"); return err }(), arithmetic.ErrInvalidBigInt)
+	if f, err := arithmetic.BigFactorial(200); err != nil || f.NumDigits() != 375 {
+		panic("200! should have 375 digits")
+	}
+	if p, _ := arithmetic.BigPower(arithmetic.NewBigInt(2), 100); p.Float() != math.Pow(2, 100) || p.Neg().Float() != -math.Pow(2, 100) {
+		panic("2^100 should convert to a float exactly")
+	}
+
+	bigInts, word := CurrentSession.BigInts, CurrentSession.Word
+	CurrentSession.Word = WordSize{}
+	CurrentSession.BigInts = false
+	AssertExpression("100000000000000000000", 1e20)
+	CurrentSession.BigInts = true
+	AssertBigExpression("2^100", "1267650600228229401496703205376")
+	AssertBigExpression("99999999999999999999 + 1", "100000000000000000000")
+	AssertBigExpression("-(2^70) / 3", "-393530540239137101141")
+	AssertBigExpression("factorial(30) / factorial(28)", "870")
+	AssertBigExpression("abs(-2^64)", "18446744073709551616")
+	AssertExpression("2^-2", 0.25)
+	AssertExpressionFails("2^100 / 0")
+	AssertExpressionFails("factorial(50000000)")
+	AssertExpressionFails("factorial(-3)")
+	AssertExpressionFails("3^1000000")
+	AssertExpressionFails("(10^10000)^1000")
+	AssertBigExpression("(10^10)^10", "1"+strings.Repeat("0", 100))
+	if v, err := EvaluateExpression("2^100 * 1.0", NewScope()); err != nil || v.AsFloat() != math.Pow(2, 100) {
+		panic(fmt.Sprintf("2^100 * 1.0 gave %v, %v, expected exactly 2^100", v, err))
+	}
+	CurrentSession.BigInts, CurrentSession.Word = bigInts, word
+
+	PrintAllTestsOk()
+}
+
+// TestPrecision checks that the series computed in arbitrary precision keep
+// gaining correct digits with more terms.
+func TestPrecision() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Precision Tests ...                                 |")
+
+	const pi = "3.1415926535897932384626433832795028841971693993751"
+	precision, angle := CurrentSession.Precision, CurrentSession.Angle
+	CurrentSession.Precision, CurrentSession.Angle = 50, Radians
+	AssertPrecise("pi(40)", pi)
+	AssertPrecise("4 * arctan(1, 100)", pi)
+	AssertPrecise("sin(1, 40)", "0.84147098480789650665250232163029899962256306079837")
+	AssertPrecise("cos(1, 40)", "0.54030230586813971740093660744297660373231042061792")
+	AssertPrecise("exponent(1, 60)", "2.7182818284590452353602874713526624977572470937")
+	AssertPrecise("ln(10, 200)", "2.3025850929940456840179914546843642076011014886288")
+	AssertPrecise("0.1 + 0.2", "0.3")
+	AssertPrecise("0.5^3", "0.125")
+	AssertPrecise("abs(-0.5)", "0.5")
+	AssertExpression("sqrt(2.25)", 1.5)
+	if v, _ := EvaluateExpression("sin(1)", NewScope()); FormatPrecise(v.Precise) == "0.84147098480789650665250232163029899962256306079837" {
+		panic("sin(1) with the default terms should not have all 50 digits right")
+	}
+	AssertExpressionFails("ln(0, 10)")
+	AssertExpressionFails("1.5 / 0")
+	AssertPrecise("arctan(1, 100) -> deg", "45")
+	AssertPrecise("180deg", pi)
+	CurrentSession.Angle = Degrees
+	AssertPrecise("sin(30, 40)", "0.5")
+	AssertPrecise("sin(30deg, 40)", "0.5")
+	AssertPrecise("180 -> rad", pi)
+	if _, err := ParsePrecision("0"); err == nil {
+		panic("ParsePrecision should have rejected 0 digits")
+	}
+	format := CurrentSession.Format
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	apply := addSettingFlags(fs)
+	if err := fs.Parse([]string{"--precision", "30", "--digits", "3"}); err != nil || apply() != nil {
+		panic("--precision 30 --digits 3 should have been accepted")
+	}
+	if CurrentSession.Precision != 30 || CurrentSession.Format.Digits != 3 {
+		panic("--precision should set the precision and --digits the digits of the format")
+	}
+	for command, digits := range map[string]int{"digits 40": 40, "precision 45": 45, "digits off": 0} {
+		var err error
+		CaptureOutput(func() { _, err = ExecuteSessionCommand(command) })
+		if err != nil || CurrentSession.Precision != digits {
+			panic(fmt.Sprintf("%s should have set the precision to %d digits", command, digits))
+		}
+	}
+	CurrentSession.Precision, CurrentSession.Angle, CurrentSession.Format = precision, angle, format
+
+	PrintAllTestsOk()
+}
+
+// AssertPrecise evaluates an expression and compares the digits it is printed
+// with to the expected ones.
+func AssertPrecise(input, expected string) {
+	v, err := EvaluateExpression(input, NewScope())
+	if err != nil {
+		panic(err)
+	}
+	if v.Kind != PreciseKind {
+		panic(input + ": expected a precise result")
+	}
+	if s := FormatPrecise(v.Precise); s != expected {
+		panic(input + ": expected " + expected + ", got " + s)
+	}
+}
+
+// TestFractions checks the fraction arithmetic and the fraction mode.
+func TestFractions() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Fraction Tests ...                                  |")
+
+	f, err := arithmetic.NewFraction(14, -6)
+	if err != nil {
+		panic(err)
+	}
+	AssertBigInt(f.Numerator(), "-7")
+	AssertBigInt(f.Denominator(), "3")
+	if s := f.MixedString(); s != "-2 1/3" {
+		panic("unexpected mixed number " + s)
+	}
+	AssertOrPanicInt(arithmetic.GCD(84, -36), 12)
+	_, err = arithmetic.NewFraction(1, 0)
+	AssertError(err, arithmetic.ErrDivisionByZero)
+	half, _ := arithmetic.NewFraction(1, 2)
+	f, err = arithmetic.FractionPower(half, 100)
+	if err != nil {
+		panic(err)
+	}
+	AssertBigInt(f.Denominator(), "1267650600228229401496703205376")
+	AssertOrPanic(f.Float()*math.Pow(2, 100), 1)
+	AssertOrPanicInt(MustInt(arithmetic.LongDivision(1<<62, 1)), 1<<62)
+
+	mode, word := CurrentSession.Fractions, CurrentSession.Word
+	CurrentSession.Word = WordSize{}
+	CurrentSession.Fractions = MixedFractions
+	AssertDisplay("89/24", "3 17/24")
+	AssertDisplay("1/3 + 1/6", "1/2")
+	AssertDisplay("(2/3)^-3", "3 3/8")
+	AssertDisplay("abs(-7/3)", "2 1/3")
+	AssertExpression("1/3 * 3", 1)
+	AssertExpression("89/24 -> dec", 89.0/24)
+	AssertExpression("1/4 + 0.5", 0.75)
+	AssertExpressionFails("1/0")
+	CurrentSession.Fractions = ImproperFractions
+	AssertDisplay("(2/3)^100", "1267650600228229401496703205376/515377520732011331036461129765621272702107522001")
+	AssertExpression("(2/3)^1000 * (3/2)^1000", 1)
+	AssertExpressionFails("(2/3)^100000")
+	bigInts := CurrentSession.BigInts
+	CurrentSession.BigInts = true
+	AssertDisplay("10^30/7", "1000000000000000000000000000000/7")
+	AssertBigExpression("10^30/7 * 14", "2000000000000000000000000000000")
+	AssertExpressionFails("(1/10^10000)^1000")
+	CurrentSession.BigInts = bigInts
+	CurrentSession.Fractions = FractionsOff
+	AssertExpression("89/24", 3)
+	CurrentSession.Fractions, CurrentSession.Word = mode, word
+
+	PrintAllTestsOk()
+}
+
+// TestComplex runs tests on complex numbers and the functions extended to the
+// complex plane.
+func TestComplex() {
+	fmt.Println("===============================================================")
+	fmt.Println("| Running Complex Number Tests ...                            |")
+
+	z, err := arithmetic.ComplexSquareRoot(-4, DefaultMarginOfError)
+	if err != nil {
+		panic(err)
+	}
+	AssertOrPanicComplex(z, 2i)
+	ln, err := arithmetic.ComplexNaturalLog(-1, DefaultTerms)
+	if err != nil {
+		panic(err)
+	}
+	AssertOrPanicComplex(ln, complex(0, math.Pi))
+	_, err = arithmetic.ComplexNaturalLog(0, DefaultTerms)
+	AssertError(err, arithmetic.ErrLogDomain)
+	AssertOrPanicComplex(trig.ComplexSine(1+1i, DefaultTerms), 1.2984575814159773+0.6349639147847361i)
+	_, err = trig.ComplexInverseTangent(1i, DefaultTerms)
+	AssertError(err, trig.ErrInverseTangentPole)
+
+	angle, form := CurrentSession.Angle, CurrentSession.Complex
+	CurrentSession.Angle = Radians
+	CurrentSession.Complex = RectangularForm
+	AssertComplex("(1+2i)*(3-i)", 5+5i)
+	AssertComplex("(1+2i)/(3-i)", 0.1+0.7i)
+	AssertComplex("sqrt(3+4i)", 2+1i)
+	AssertComplex("sqrt(-4)", 2i)
+	AssertComplex("(-8)^(1/3.0)", 1+1.7320508075688772i)
+	AssertComplex("i^i", 0.20787957635076193)
+	AssertComplex("i^2", -1)
+	AssertComplex("ln(-1)", complex(0, math.Pi))
+	AssertComplex("log(-100)", 2+1.3643763538418412i)
+	AssertComplex("exponent(1+i)", 1.4686939399158851+2.2873552871788423i)
+	AssertComplex("exponent(i*pi)", -1)
+	AssertComplex("cos(1+i)", 0.8337300251311491-0.9888977057628651i)
+	AssertComplex("tan(1+i)", 0.2717525853195118+1.0839233273386946i)
+	AssertComplex("arcsin(2)", 1.5707963267948966+1.3169578969248166i)
+	AssertComplex("arccos(0.5+i)", 1.2213572639376833-0.9261330313501823i)
+	AssertComplex("arctan(2i)", 1.5707963267948966+0.5493061443340549i)
+	AssertComplex("conj(3+4i)", 3-4i)
+	AssertExpression("modulus(3+4i)", 5)
+	AssertExpression("abs(-3+4i)", 5)
+	AssertExpression("arg(-1)", math.Pi)
+	AssertDisplay("2-3i", "2.00000 - 3.00000i")
+	AssertExpressionFails("ln(0)")
+	AssertExpressionFails("arctan(i)")
+	AssertExpressionFails("2i / 0")
+	AssertExpressionFails("mean(1, i)")
+	AssertExpressionFails("5 & i")
+	CurrentSession.Complex = PolarForm
+	AssertDisplay("-2i", "2.00000 ∠ -1.57080")
+	CurrentSession.Angle = Degrees
+	AssertComplex("sin(90i)", complex(0, math.Sinh(math.Pi/2)))
+	AssertComplex("arcsin(2)", complex(90, 1.3169578969248166*180/math.Pi))
+	AssertComplex("arcsin(2) -> rad", 1.5707963267948966+1.3169578969248166i)
+	AssertComplex("sin(arcsin(2))", 2)
+	AssertComplex("arcsin(sin(30+2i))", 30+2i)
+	AssertDisplay("1+i", "1.41421 ∠ 45.00000deg")
+	v, _ := EvaluateExpression("sin(1+i)", NewScope())
+	CurrentSession.Angle = Radians
+	AssertComplex("sin((1+i)deg)", v.AsComplex())
+	CurrentSession.Angle, CurrentSession.Complex = angle, form
+
+	PrintAllTestsOk()
+}
+
+// AssertComplex evaluates an expression and compares both parts of its value
+// to the expected complex number.
+func AssertComplex(input string, expected complex128) {
+	v, err := EvaluateExpression(input, NewScope())
+	if err != nil {
+		panic(err)
+	}
+	AssertOrPanicComplex(v.AsComplex(), expected)
+}
+
+// AssertOrPanicComplex ensures both parts of two complex numbers are within a
+// reasonable margin compared to each other.
+func AssertOrPanicComplex(x, y complex128) {
+	AssertOrPanic(real(x), real(y))
+	AssertOrPanic(imag(x), imag(y))
+}
+
+// AssertDisplay evaluates an expression and compares the way its value is
+// printed to the expected one.
+func AssertDisplay(input, expected string) {
+	v, err := EvaluateExpression(input, NewScope())
+	if err != nil {
+		panic(err)
+	}
+	if s := v.String(); s != expected {
+		panic(input + ": expected " + expected + ", got " + s)
+	}
+}
+
+// AssertBigInt ensures that a BigInt has the expected digits.
+func AssertBigInt(x arithmetic.BigInt, expected string) {
+	if x.String() != expected {
+		panic("Expected " + expected + ", got " + x.String())
+	}
+}
+
+// AssertBigExpression evaluates an expression and compares its digits to the
+// expected ones.
+func AssertBigExpression(input, expected string) {
+	v, err := EvaluateExpression(input, NewScope())
+	if err != nil {
+		panic(err)
+	}
+	x, ok := v.AsBigInt()
+	if !ok {
+		panic(input + ": expected an integer")
+	}
+	AssertBigInt(x, expected)
+}
+
+// AssertExpression evaluates an expression and compares it to the expected
+// value.
+func AssertExpression(input string, expected float64) {
+	v, err := EvaluateExpression(input, NewScope())
+	if err != nil {
+		panic(err)
+	}
+	AssertOrPanic(v.AsFloat(), expected)
+}
+
+// AssertFunction ensures that calling a registered function with args gives
+// the expected result.
+func AssertFunction(name string, expected float64, args ...Value) {
+	v, err := CallBuiltin(name, args)
+	if err != nil {
+		panic(err)
+	}
+	AssertOrPanic(v.AsFloat(), expected)
+}
+
+// AssertInvoke ensures that invoking f with args gives the expected result.
+func AssertInvoke(f *Function, expected float64, args ...Value) {
+	v, err := f.Invoke(args)
+	if err != nil {
+		panic(err)
+	}
+	AssertOrPanic(v.AsFloat(), expected)
+}
+
+// AssertExpressionFails ensures that an expression is rejected with an error.
+func AssertExpressionFails(input string) {
+	if _, err := EvaluateExpression(input, NewScope()); err == nil {
+		panic("Expression should have failed: " + input)
+	}
+}
+
 // AssertLogIsClose ensures that the values for ln() and log() are within a
 // reasonable margin of error.
 func AssertLogIsClose(x, y float64) {
@@ -95,6 +1355,29 @@ func AssertLogIsClose(x, y float64) {
 	}
 }
 
+// Must returns the result of a library function, panicking if it failed.
+func Must(v float64, err error) float64 {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// MustInt returns the result of a library function, panicking if it failed.
+func MustInt(v int, err error) int {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// AssertError panics unless err is target.
+func AssertError(err, target error) {
+	if !errors.Is(err, target) {
+		panic(fmt.Sprintf("got error %v, expected %v", err, target))
+	}
+}
+
 // AssertOrPanic ensures two floats are within a reasonable margin compared to
 // each other.
 func AssertOrPanic(x, y float64) {
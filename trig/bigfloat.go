@@ -0,0 +1,67 @@
+package trig
+
+import (
+	"math/big"
+
+	"calculator/arithmetic"
+)
+
+/**
+This file contains the series of Sine, Cosine, Tangent and InverseTangent
+evaluated in arbitrary precision. Results have the precision of x.
+*/
+
+// BigSine computes sin(x) with the Taylor Series x - x^3/3! + x^5/5! - ...
+// expanded to n terms after x, like Sine.
+func BigSine(x *big.Float, n int) *big.Float {
+	return alternatingSeries(x, new(big.Float).SetPrec(x.Prec()).Set(x), 1, n)
+}
+
+// BigCosine computes cos(x) with the Taylor Series 1 - x^2/2! + x^4/4! - ...
+// expanded to n terms after 1, like Cosine.
+func BigCosine(x *big.Float, n int) *big.Float {
+	return alternatingSeries(x, new(big.Float).SetPrec(x.Prec()).SetInt64(1), 0, n)
+}
+
+// alternatingSeries adds n terms to first, the term x^k/k!, each multiplied by
+// -x^2 and divided by the next two factors of the factorial.
+func alternatingSeries(x, first *big.Float, k, n int) *big.Float {
+	prec := x.Prec()
+	minusXSquared := new(big.Float).SetPrec(prec).Mul(x, x)
+	minusXSquared.Neg(minusXSquared)
+	term := new(big.Float).SetPrec(prec).Set(first)
+	sum := new(big.Float).SetPrec(prec).Set(first)
+	for i := 0; i < n; i++ {
+		term.Mul(term, minusXSquared)
+		term.Quo(term, new(big.Float).SetPrec(prec).SetInt64(int64((k+1)*(k+2))))
+		sum.Add(sum, term)
+		k += 2
+	}
+	return sum
+}
+
+// BigTangent computes tan(x) as sin(x) / cos(x).
+func BigTangent(x *big.Float, n int) *big.Float {
+	sine := BigSine(x, n)
+	return sine.Quo(sine, BigCosine(x, n))
+}
+
+// BigInverseTangent computes arctan(x). The series of arctan converges slowly
+// for |x| near 1 and not at all beyond, so x is first halved as an angle with
+// arctan(x) = 2 arctan(x / (1 + sqrt(1 + x^2))) until |x| <= 1/2, then the
+// series is expanded to n terms.
+func BigInverseTangent(x *big.Float, n int) *big.Float {
+	prec := x.Prec()
+	one := new(big.Float).SetPrec(prec).SetInt64(1)
+	half := new(big.Float).SetPrec(prec).SetFloat64(0.5)
+	x = new(big.Float).SetPrec(prec).Set(x)
+	doublings := 0
+	for new(big.Float).Abs(x).Cmp(half) > 0 {
+		root := new(big.Float).SetPrec(prec).Mul(x, x)
+		root.Sqrt(root.Add(root, one))
+		x.Quo(x, root.Add(root, one))
+		doublings++
+	}
+	v := arithmetic.ArctanSeries(x, n)
+	return v.SetMantExp(v, doublings)
+}
@@ -0,0 +1,89 @@
+package trig
+
+import (
+	"errors"
+	"math"
+
+	"calculator/arithmetic"
+)
+
+/**
+This file extends the trigonometric functions to complex numbers. Sine and
+Cosine use their Taylor Series with complex arithmetic, and the inverse
+functions are expressed through the complex logarithm, whose series is expanded
+to the same number of terms:
+
+    arcsin(z) = -i ln(iz + sqrt(1 - z^2))
+    arccos(z) = pi/2 - arcsin(z)
+    arctan(z) = i/2 (ln(1 - iz) - ln(1 + iz))
+*/
+
+// ErrInverseTangentPole is returned by ComplexInverseTangent for i and -i.
+var ErrInverseTangentPole = errors.New("arctan is undefined at i and -i")
+
+// ComplexSine computes sin(z) with the Taylor Series of Sine expanded to n
+// terms after z.
+func ComplexSine(z complex128, n int) complex128 {
+	return complexAlternatingSeries(z, z, 1, n)
+}
+
+// ComplexCosine computes cos(z) with the Taylor Series of Cosine expanded to n
+// terms after 1.
+func ComplexCosine(z complex128, n int) complex128 {
+	return complexAlternatingSeries(z, 1, 0, n)
+}
+
+// complexAlternatingSeries adds n terms to first, the term z^k/k!, each
+// multiplied by -z^2 and divided by the next two factors of the factorial.
+func complexAlternatingSeries(z, first complex128, k, n int) complex128 {
+	term, sum := first, first
+	for i := 0; i < n; i++ {
+		term *= -z * z / complex(float64((k+1)*(k+2)), 0)
+		sum += term
+		k += 2
+	}
+	return sum
+}
+
+// ComplexTangent computes tan(z) as sin(z) / cos(z).
+func ComplexTangent(z complex128, n int) complex128 {
+	return ComplexSine(z, n) / ComplexCosine(z, n)
+}
+
+// ComplexInverseSine computes the principal value of arcsin(z). The root
+// sqrt(1 - z^2) is taken as sqrt(1 - z) sqrt(1 + z) with ComplexSquareRoot,
+// which puts the branch cuts on the real axis beyond -1 and 1, and since
+// (iz + w)(w - iz) = 1 for that root w, the logarithm of whichever sum does
+// not cancel is used.
+func ComplexInverseSine(z complex128, n int) (complex128, error) {
+	a, _ := arithmetic.ComplexSquareRoot(complex(1-real(z), -imag(z)), arithmetic.ExactMargin)
+	b, _ := arithmetic.ComplexSquareRoot(1+z, arithmetic.ExactMargin)
+	w := a * b
+	if arithmetic.Modulus(1i*z+w) < arithmetic.Modulus(w-1i*z) {
+		ln, err := arithmetic.ComplexNaturalLog(w-1i*z, n)
+		return 1i * ln, err
+	}
+	ln, err := arithmetic.ComplexNaturalLog(1i*z+w, n)
+	return -1i * ln, err
+}
+
+// ComplexInverseCosine computes the principal value of arccos(z).
+func ComplexInverseCosine(z complex128, n int) (complex128, error) {
+	inverseSine, err := ComplexInverseSine(z, n)
+	return complex(math.Pi/2, 0) - inverseSine, err
+}
+
+// ComplexInverseTangent computes the principal value of arctan(z). Both
+// logarithms are taken separately, so that on the branch cuts the result is
+// continuous with the side of the real part's sign.
+func ComplexInverseTangent(z complex128, n int) (complex128, error) {
+	if z == 1i || z == -1i {
+		return 0, ErrInverseTangentPole
+	}
+	a, err := arithmetic.ComplexNaturalLog(1-1i*z, n)
+	if err != nil {
+		return 0, err
+	}
+	b, err := arithmetic.ComplexNaturalLog(1+1i*z, n)
+	return 0.5i * (a - b), err
+}
@@ -1,9 +1,19 @@
-package main
+// Package trig implements the trigonometric functions and their inverses
+// through Taylor Series expanded to a given number of terms. Angles are in
+// radians.
+package trig
 
 import (
+	"errors"
 	"math"
+
+	"calculator/arithmetic"
 )
 
+// ErrDomain is returned by InverseSine and InverseCosine for x outside
+// -1 <= x <= 1.
+var ErrDomain = errors.New("domain is between -1 <= x <= 1 inclusive")
+
 // Sine returns the value of sin(x) where x is in radians. x can be negative or
 // positive. sin(x) is calculated by expressing the sin value in a Taylor Series
 // for all real numbers x (where x is the angle in radians). The series will be
@@ -15,7 +25,7 @@ func Sine(x float64, n int) float64 {
 	denominator := -3.0
 
 	for i := 0; i < n; i++ {
-		sineValue += ToThePowerFloat(x, power) / Factorial(denominator)
+		sineValue += arithmetic.ToThePowerFloat(x, power) / signedFactorial(denominator)
 		if denominator < 0 {
 			denominator = (denominator * -1) + 2
 		} else {
@@ -32,9 +42,9 @@ func Sine(x float64, n int) float64 {
 // The series will be expanded to n terms to compute the value of arcsin(x).
 // More on the series can found here:
 // https://www.mathportal.org/formulas/pdf/taylor-series-formulas.pdf
-func InverseSine(x float64, n int) float64 {
+func InverseSine(x float64, n int) (float64, error) {
 	if x > 1 || x < -1 {
-		panic("Domain of arcsin is between -1 < x < 1 inclusive")
+		return 0, ErrDomain
 	}
 
 	inverseSineValue := x
@@ -46,7 +56,7 @@ func InverseSine(x float64, n int) float64 {
 
 	for i := 1; i <= n; i++ {
 		a := numeratorProduct / denominatorProduct
-		b := ToThePowerFloat(x, power) / power
+		b := arithmetic.ToThePowerFloat(x, power) / power
 		inverseSineValue += a * b
 
 		power += 2.0
@@ -55,7 +65,7 @@ func InverseSine(x float64, n int) float64 {
 		denominatorBase += 2.0
 		denominatorProduct *= denominatorBase
 	}
-	return inverseSineValue
+	return inverseSineValue, nil
 }
 
 // Tangent returns the value of tan(x) where x is in radians.
@@ -75,7 +85,7 @@ func InverseTangent(x float64, n int) float64 {
 	denominator := 3.0
 
 	for i := 0; i < n; i++ {
-		v += ToThePowerFloat(x, power) / denominator
+		v += arithmetic.ToThePowerFloat(x, power) / denominator
 		if denominator < 0 {
 			denominator = (denominator * -1) + 2
 		} else {
@@ -106,7 +116,7 @@ func Cosine(x float64, n int) float64 {
 	denominator := -2.0
 
 	for i := 0; i < n; i++ {
-		cosineValue += ToThePowerFloat(x, power) / Factorial(denominator)
+		cosineValue += arithmetic.ToThePowerFloat(x, power) / signedFactorial(denominator)
 		if denominator < 0 {
 			denominator = (denominator * -1) + 2
 		} else {
@@ -118,11 +128,31 @@ func Cosine(x float64, n int) float64 {
 }
 
 // InverseCosine computes and returns arccos(x) where -1 <= x <= 1.
-func InverseCosine(x float64, n int) float64 {
-	return math.Pi/2 - InverseSine(x, n)
+func InverseCosine(x float64, n int) (float64, error) {
+	inverseSineValue, err := InverseSine(x, n)
+	if err != nil {
+		return 0, err
+	}
+	return math.Pi/2 - inverseSineValue, nil
 }
 
 // ConvertToRadian converts degree to radian.
 func ConvertToRadian(x float64) float64 {
 	return (x / 180.0) * math.Pi
 }
+
+// ConvertFromRadian converts radian to degree.
+func ConvertFromRadian(x float64) float64 {
+	return (x / math.Pi) * 180.0
+}
+
+// signedFactorial returns denominator!, negative for negative denominators,
+// which alternates the signs of the terms of a series. Denominators are always
+// whole numbers.
+func signedFactorial(denominator float64) float64 {
+	f, _ := arithmetic.Factorial(math.Abs(denominator))
+	if denominator < 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/**
+This file contains the functions users define at the main prompt, e.g.
+f(x, y) = sqrt(x^2 + y^2). The body of a function is kept as an expression tree
+and evaluated every time the function is called, so a function may call other
+user-defined functions, including ones defined after it.
+*/
+
+// MaxCallDepth limits how deeply user-defined functions can call each other,
+// so that a function calling itself fails instead of overflowing the stack.
+const MaxCallDepth = 256
+
+// UserFunction is a function defined at the main prompt.
+type UserFunction struct {
+	Name   string
+	Params []string
+	Body   Node
+	Source string
+}
+
+// String returns the definition of the function as it was typed.
+func (f *UserFunction) String() string {
+	return fmt.Sprintf("%s(%s) = %s", f.Name, strings.Join(f.Params, ", "), f.Source)
+}
+
+// Call binds args to the parameters of f and evaluates its body.
+func (f *UserFunction) Call(args []Value, scope *Scope) (Value, error) {
+	if len(args) != len(f.Params) {
+		return Value{}, fmt.Errorf("%s expects %d arguments, got %d", f.Name, len(f.Params), len(args))
+	}
+	if scope.depth >= MaxCallDepth {
+		return Value{}, fmt.Errorf("maximum call depth exceeded in %s", f.Name)
+	}
+	variables := make(map[string]Value, len(args))
+	for i, param := range f.Params {
+		variables[param] = args[i]
+	}
+	return f.Body.Eval(scope.child(variables))
+}
+
+// FunctionDefinition is a statement that defines a user function.
+type FunctionDefinition struct {
+	Function *UserFunction
+}
+
+// Eval stores the function in the scope. A definition has no value.
+func (d FunctionDefinition) Eval(scope *Scope) (Value, error) {
+	scope.Functions[d.Function.Name] = d.Function
+	return Value{}, nil
+}
+
+// matchFunctionHeader checks whether tokens start with name(a, b, ...) = and
+// returns the parameter names and the index of the first token of the body.
+func matchFunctionHeader(tokens []Token) ([]string, int, bool) {
+	if len(tokens) < 4 || tokens[0].Kind != IdentifierToken || tokens[1].Text != "(" {
+		return nil, 0, false
+	}
+	params := make([]string, 0)
+	i := 2
+	if tokens[i].Text != ")" {
+		for {
+			if tokens[i].Kind != IdentifierToken {
+				return nil, 0, false
+			}
+			params = append(params, tokens[i].Text)
+			i++
+			if tokens[i].Text != "," {
+				break
+			}
+			i++
+		}
+	}
+	if tokens[i].Text != ")" || tokens[i+1].Text != "=" {
+		return nil, 0, false
+	}
+	return params, i + 2, true
+}
+
+// parseFunctionDefinition parses the body of a function whose header has
+// already been matched by matchFunctionHeader.
+func parseFunctionDefinition(input string, tokens []Token, params []string, bodyStart int) (Node, error) {
+	name := tokens[0].Text
+	if _, ok := LookupFunction(name); ok {
+		return nil, fmt.Errorf("cannot redefine built-in function %s", name)
+	}
+	seen := make(map[string]bool)
+	for _, param := range params {
+		if seen[param] {
+			return nil, fmt.Errorf("duplicate parameter %s in %s", param, name)
+		}
+		seen[param] = true
+	}
+
+	p := &Parser{tokens: tokens, pos: bodyStart}
+	body, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectEnd(); err != nil {
+		return nil, err
+	}
+	source := strings.TrimSpace(string([]rune(input)[tokens[bodyStart-1].Position+1:]))
+	return FunctionDefinition{&UserFunction{name, params, body, source}}, nil
+}
+
+// PrintFunctions lists every user-defined function in alphabetical order.
+func PrintFunctions(scope *Scope) {
+	if len(scope.Functions) == 0 {
+		fmt.Println("No functions defined.")
+		return
+	}
+	names := make([]string, 0, len(scope.Functions))
+	for name := range scope.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println(scope.Functions[name])
+	}
+	fmt.Println("===============================================================")
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"calculator/arithmetic"
+)
+
+/**
+This file contains the value type produced by the expression evaluator and the
+operators that can be applied to it.
+*/
+
+// ValueKind identifies what a Value holds.
+type ValueKind int
+
+const (
+	// IntKind values are exact integers.
+	IntKind ValueKind = iota
+	// FloatKind values are real numbers.
+	FloatKind
+	// ListKind values are data sets used by the stats functions.
+	ListKind
+	// BigKind values are integers too large for an int, computed in the big
+	// integer mode.
+	BigKind
+	// PreciseKind values are real numbers computed in arbitrary precision
+	// while digits is set.
+	PreciseKind
+	// FracKind values are exact fractions, computed in the fraction mode.
+	FracKind
+	// ComplexKind values are complex numbers with a non-zero imaginary part.
+	ComplexKind
+)
+
+// Value is the result of evaluating an expression. Integers are kept exact so
+// that they can be routed to the bitwise arithmetic functions, integers too
+// large for an int are kept as a Big in the big integer mode and the results
+// of precise series as a Precise, quotients in the fraction mode are kept as
+// a Frac, complex numbers as a Complex, everything else is carried as a float.
+type Value struct {
+	Kind    ValueKind
+	Int     int
+	Float   float64
+	List    []float64
+	Big     arithmetic.BigInt
+	Precise *big.Float
+	Frac    arithmetic.Fraction
+	Complex complex128
+}
+
+// IntValue wraps an int in a Value.
+func IntValue(x int) Value {
+	return Value{Kind: IntKind, Int: x}
+}
+
+// FloatValue wraps a float in a Value.
+func FloatValue(x float64) Value {
+	return Value{Kind: FloatKind, Float: x}
+}
+
+// ListValue wraps a data set in a Value.
+func ListValue(data []float64) Value {
+	return Value{Kind: ListKind, List: data}
+}
+
+// IsNumber reports whether v is a single number rather than a data set.
+func (v Value) IsNumber() bool {
+	return v.Kind != ListKind
+}
+
+// AsFloat returns the numeric value of v as a float, the real part of a
+// complex number.
+func (v Value) AsFloat() float64 {
+	switch v.Kind {
+	case IntKind:
+		return float64(v.Int)
+	case BigKind:
+		return v.Big.Float()
+	case PreciseKind:
+		x, _ := v.Precise.Float64()
+		return x
+	case FracKind:
+		return v.Frac.Float()
+	case ComplexKind:
+		return real(v.Complex)
+	}
+	return v.Float
+}
+
+// AsInt returns the value of v as an int if v holds a whole number.
+func (v Value) AsInt() (int, bool) {
+	switch v.Kind {
+	case IntKind:
+		return v.Int, true
+	case FloatKind:
+		if v.Float == math.Trunc(v.Float) && arithmetic.AbsFloat(v.Float) < 1<<62 {
+			return int(v.Float), true
+		}
+	case PreciseKind:
+		if x, accuracy := v.Precise.Int64(); accuracy == big.Exact && arithmetic.Abs(int(x)) < 1<<62 {
+			return int(x), true
+		}
+	}
+	return 0, false
+}
+
+// AsList returns v as a data set. A single number is treated as a data set of
+// one element.
+func (v Value) AsList() []float64 {
+	if v.Kind == ListKind {
+		return v.List
+	}
+	return []float64{v.AsFloat()}
+}
+
+// String formats v the same way the rest of the calculator prints results.
+func (v Value) String() string {
+	switch v.Kind {
+	case IntKind:
+		return FormatInteger(v.Int)
+	case FloatKind:
+		return FormatNumber(v.Float)
+	case BigKind:
+		return v.Big.String()
+	case PreciseKind:
+		return FormatPrecise(v.Precise)
+	case FracKind:
+		return FormatFraction(v.Frac)
+	case ComplexKind:
+		return FormatComplex(v.Complex)
+	default:
+		elements := make([]string, len(v.List))
+		for i, x := range v.List {
+			elements[i] = FormatNumber(x)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	}
+}
+
+// ErrDivisionByZero is returned when dividing by zero.
+var ErrDivisionByZero = arithmetic.ErrDivisionByZero
+
+// ApplyBinaryOperator computes x op y. Operations on two ints are routed to the
+// bitwise and long-hand implementations in arithmetic.go, to the big integers
+// of bigint.go in the big integer mode, or to the fractions of fraction.go in
+// the fraction mode. Anything involving a complex number is computed in
+// complex.go, anything involving a precise value in arbitrary precision, see
+// precise.go, and anything else involving a float in floating point.
+func ApplyBinaryOperator(op string, x, y Value) (Value, error) {
+	if !x.IsNumber() || !y.IsNumber() {
+		return Value{}, fmt.Errorf("operator %s is not defined on data sets", op)
+	}
+	if IsBitwiseOperator(op) {
+		a, aOk := x.AsInt()
+		b, bOk := y.AsInt()
+		if !aOk || !bOk {
+			return Value{}, fmt.Errorf("operator %s is only defined on integers", op)
+		}
+		if w := CurrentSession.Word; w.Enabled() {
+			return applyWordOperator(w, op, a, b)
+		}
+		return applyBitwiseOperator(op, a, b)
+	}
+	if w := CurrentSession.Word; w.Enabled() && x.Kind == IntKind && y.Kind == IntKind {
+		return applyWordOperator(w, op, x.Int, y.Int)
+	}
+	if FractionsEnabled() && needsFraction(op, x, y) {
+		a, aOk := x.AsFraction()
+		b, bOk := y.AsFraction()
+		if aOk && bOk {
+			return applyFractionOperator(op, a, b)
+		}
+	}
+	if x.Kind == ComplexKind || y.Kind == ComplexKind {
+		return applyComplexOperator(op, x.AsComplex(), y.AsComplex())
+	}
+	if x.Kind == IntKind && y.Kind == IntKind && !BigIntsEnabled() {
+		return applyIntOperator(op, x.Int, y.Int)
+	}
+	if a, ok := x.AsBigInt(); ok && BigIntsEnabled() {
+		if b, ok := y.AsBigInt(); ok {
+			return applyBigOperator(op, a, b)
+		}
+	}
+	if (x.Kind == PreciseKind || y.Kind == PreciseKind) && PrecisionEnabled() {
+		return applyPreciseOperator(op, x.AsPrecise(), y.AsPrecise())
+	}
+	return applyFloatOperator(op, x.AsFloat(), y.AsFloat())
+}
+
+// needsFraction reports whether x op y can leave the integers, which in the
+// fraction mode is a quotient, a negative power or anything involving a
+// fraction.
+func needsFraction(op string, x, y Value) bool {
+	switch {
+	case x.Kind == FracKind || y.Kind == FracKind:
+		return true
+	case op == "/":
+		return true
+	case op == "^":
+		return y.Kind == IntKind && y.Int < 0
+	}
+	return false
+}
+
+// IsBitwiseOperator reports whether op only applies to integers. Besides the
+// infix operators & | << >> these are the names xor, rol and ror.
+func IsBitwiseOperator(op string) bool {
+	switch op {
+	case "&", "|", "<<", ">>", "xor", "rol", "ror":
+		return true
+	}
+	return false
+}
+
+func applyBitwiseOperator(op string, x, y int) (Value, error) {
+	switch op {
+	case "&":
+		return IntValue(arithmetic.BitwiseAnd(x, y)), nil
+	case "|":
+		return IntValue(arithmetic.BitwiseOr(x, y)), nil
+	case "xor":
+		return IntValue(arithmetic.BitwiseXor(x, y)), nil
+	}
+	if y < 0 {
+		return Value{}, errors.New("shift count must not be negative")
+	}
+	switch op {
+	case "<<":
+		return IntValue(arithmetic.ShiftLeft(x, y)), nil
+	case ">>":
+		return IntValue(arithmetic.ShiftRight(x, y)), nil
+	case "rol":
+		return IntValue(arithmetic.RotateLeft(x, y)), nil
+	default:
+		return IntValue(arithmetic.RotateRight(x, y)), nil
+	}
+}
+
+// applyIntOperator computes x op y on ints. Results that do not fit in an int
+// are computed in floating point instead of wrapping around.
+func applyIntOperator(op string, x, y int) (Value, error) {
+	if intOverflows(op, x, y) {
+		return applyFloatOperator(op, float64(x), float64(y))
+	}
+	return applyWrappingOperator(op, x, y)
+}
+
+// applyWrappingOperator computes x op y on ints, wrapping around on overflow
+// like the word sizes do.
+func applyWrappingOperator(op string, x, y int) (Value, error) {
+	switch op {
+	case "+":
+		return IntValue(arithmetic.BitwiseAdd(x, y)), nil
+	case "-":
+		return IntValue(arithmetic.BitwiseSubtractFast(x, y)), nil
+	case "*":
+		return IntValue(arithmetic.KaratsubaMultiplicationFast(x, y)), nil
+	case "/":
+		v, err := arithmetic.LongDivision(x, y)
+		return IntValue(v), err
+	case "^":
+		if y < 0 {
+			return FloatValue(math.Pow(float64(x), float64(y))), nil
+		}
+		v, err := arithmetic.ToThePowerInt(x, y)
+		return IntValue(v), err
+	}
+	return Value{}, fmt.Errorf("unknown operator %s", op)
+}
+
+// intOverflows reports whether x op y is too large for an int.
+func intOverflows(op string, x, y int) bool {
+	switch op {
+	case "+":
+		sum := x + y
+		return (x > 0 && y > 0 && sum < 0) || (x < 0 && y < 0 && sum >= 0)
+	case "-":
+		difference := x - y
+		return (x >= 0 && y < 0 && difference < 0) || (x < 0 && y > 0 && difference >= 0)
+	case "*":
+		product := x * y
+		return x != 0 && (product/x != y || (x == -1 && y == math.MinInt64))
+	case "^":
+		result := 1
+		for base := x; y > 0; y /= 2 {
+			if y%2 == 1 {
+				if intOverflows("*", result, base) {
+					return true
+				}
+				result *= base
+			}
+			if y > 1 {
+				if intOverflows("*", base, base) {
+					return true
+				}
+				base *= base
+			}
+		}
+	}
+	return false
+}
+
+func applyFloatOperator(op string, x, y float64) (Value, error) {
+	switch op {
+	case "+":
+		return FloatValue(x + y), nil
+	case "-":
+		return FloatValue(x - y), nil
+	case "*":
+		return FloatValue(x * y), nil
+	case "/":
+		if y == 0 {
+			return Value{}, ErrDivisionByZero
+		}
+		return FloatValue(x / y), nil
+	case "^":
+		if y >= 0 && y == math.Trunc(y) && y <= MaxExponent {
+			return FloatValue(arithmetic.ToThePowerFloat(x, y)), nil
+		}
+		if x < 0 && y != math.Trunc(y) {
+			return complexResult(arithmetic.ComplexPower(complex(x, 0), complex(y, 0)))
+		}
+		return FloatValue(math.Pow(x, y)), nil
+	}
+	return Value{}, fmt.Errorf("unknown operator %s", op)
+}
+
+// Negate returns -x.
+func Negate(x Value) (Value, error) {
+	switch x.Kind {
+	case IntKind:
+		if w := CurrentSession.Word; w.Enabled() {
+			return wordNegate(w, x.Int), nil
+		}
+		if BigIntsEnabled() {
+			return BigValue(arithmetic.NewBigInt(x.Int).Neg()), nil
+		}
+		return IntValue(arithmetic.BitwiseSubtractFast(0, x.Int)), nil
+	case FloatKind:
+		return FloatValue(-x.Float), nil
+	case BigKind:
+		return BigValue(x.Big.Neg()), nil
+	case PreciseKind:
+		return PreciseValue(new(big.Float).Neg(x.Precise)), nil
+	case FracKind:
+		return FractionValue(x.Frac.Neg()), nil
+	case ComplexKind:
+		return ComplexValue(-x.Complex), nil
+	}
+	return Value{}, errors.New("operator - is not defined on data sets")
+}
+
+// BitwiseNotValue flips every bit of the integer x.
+func BitwiseNotValue(x Value) (Value, error) {
+	n, ok := x.AsInt()
+	if !ok {
+		return Value{}, errors.New("operator ~ is only defined on integers")
+	}
+	w := CurrentSession.Word
+	return IntValue(w.Wrap(arithmetic.BitwiseNot(w.Wrap(n)))), nil
+}
+
+// wordFactorial computes n! exactly and wraps it to the word size w, raising
+// the flags for the bits that are lost.
+func wordFactorial(w WordSize, n int) Value {
+	if n > maxWordExponent {
+		// n! holds more than n/2 factors of 2, so no bit of the word is left.
+		CurrentSession.Flags.Record(WordFlags{Carry: true, Overflow: true})
+		return IntValue(0)
+	}
+	exact := new(big.Int).MulRange(1, int64(n))
+	CurrentSession.Flags.Record(WordFlags{
+		Carry:    !WordSize{w.Bits, false}.fits(exact),
+		Overflow: !WordSize{w.Bits, true}.fits(exact),
+	})
+	low := new(big.Int).And(exact, new(big.Int).SetUint64(w.mask()))
+	return IntValue(w.Wrap(int(low.Uint64())))
+}
+
+// FactorialValue computes x! for a whole number x. Results that no longer fit
+// in an int are returned as floats.
+func FactorialValue(x Value) (Value, error) {
+	n, ok := x.AsInt()
+	if !ok {
+		return Value{}, arithmetic.ErrNotWholeNumber
+	}
+	if w := CurrentSession.Word; w.Enabled() && n >= 0 {
+		return wordFactorial(w, n), nil
+	}
+	if BigIntsEnabled() {
+		if n > MaxBigFactorial {
+			return Value{}, fmt.Errorf("factorial is too large, n must be at most %d", MaxBigFactorial)
+		}
+		v, err := arithmetic.BigFactorial(n)
+		return BigValue(v), err
+	}
+	v, err := arithmetic.Factorial(float64(n))
+	if err != nil {
+		return Value{}, err
+	}
+	if arithmetic.AbsFloat(v) < 1<<62 {
+		return IntValue(int(v)), nil
+	}
+	return FloatValue(v), nil
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"calculator/arithmetic"
+)
+
+/**
+This file contains the word size setting of programmer mode, which emulates
+integer registers narrower than Go's 64-bit int, e.g.
+
+    word int16      results wrap to a signed 16-bit register
+    word uint8      results wrap to an unsigned 8-bit register
+    word off        back to plain 64-bit integers
+
+While a word size is set every integer result is wrapped to it and printed
+with its two's-complement bit pattern. The carry flag is raised when the result
+does not fit the register as an unsigned number and the overflow flag when it
+does not fit as a signed number, like the flags of a CPU.
+*/
+
+// WordSize is the width and signedness of an emulated integer register. The
+// zero value means no word size is set.
+type WordSize struct {
+	Bits   int
+	Signed bool
+}
+
+// WordFlags are the flags raised by the operations of a command.
+type WordFlags struct {
+	Carry    bool
+	Overflow bool
+}
+
+// maxWordExponent bounds the exponents and shift counts for which flags are
+// computed exactly. Anything larger overflows every register.
+const maxWordExponent = 1024
+
+// ParseWordSize reads a word size such as int16, uint8 or off.
+func ParseWordSize(name string) (WordSize, error) {
+	name = strings.ToLower(name)
+	if name == "off" {
+		return WordSize{}, nil
+	}
+	w := WordSize{Signed: !strings.HasPrefix(name, "u")}
+	bits, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(name, "u"), "int"))
+	if err != nil || !strings.Contains(name, "int") {
+		return WordSize{}, fmt.Errorf("unknown word size %s, expected int8..int64, uint8..uint64 or off", name)
+	}
+	switch bits {
+	case 8, 16, 32, 64:
+		w.Bits = bits
+		return w, nil
+	}
+	return WordSize{}, fmt.Errorf("word size must be 8, 16, 32 or 64 bits, not %d", bits)
+}
+
+// Enabled reports whether a word size is set.
+func (w WordSize) Enabled() bool {
+	return w.Bits != 0
+}
+
+// String returns the word size as it is typed, e.g. uint16.
+func (w WordSize) String() string {
+	if !w.Enabled() {
+		return "off"
+	}
+	if w.Signed {
+		return fmt.Sprintf("int%d", w.Bits)
+	}
+	return fmt.Sprintf("uint%d", w.Bits)
+}
+
+func (w WordSize) mask() uint64 {
+	return ^uint64(0) >> uint(64-w.Bits)
+}
+
+// Pattern returns the bits of x that fit in the word.
+func (w WordSize) Pattern(x int) uint64 {
+	return uint64(x) & w.mask()
+}
+
+// Wrap truncates x to the word. Signed words are sign extended, so that 0xff
+// in an int8 is -1.
+func (w WordSize) Wrap(x int) int {
+	if !w.Enabled() {
+		return x
+	}
+	pattern := w.Pattern(x)
+	if w.Signed && pattern>>uint(w.Bits-1) == 1 {
+		return int(pattern | ^w.mask())
+	}
+	return int(pattern)
+}
+
+// BitPattern returns the bits of x in groups of four, e.g. 1000 0000.
+func (w WordSize) BitPattern(x int) string {
+	digits := fmt.Sprintf("%0*b", w.Bits, w.Pattern(x))
+	groups := make([]string, 0, w.Bits/4)
+	for i := 0; i < len(digits); i += 4 {
+		groups = append(groups, digits[i:i+4])
+	}
+	return strings.Join(groups, " ")
+}
+
+// FormatInBase formats x in base. Decimal shows the value of the register,
+// the other bases show its bit pattern.
+func (w WordSize) FormatInBase(x int, base NumberBase) string {
+	pattern := w.Pattern(x)
+	decimal := strconv.FormatUint(pattern, 10)
+	if w.Signed {
+		decimal = strconv.Itoa(w.Wrap(x))
+	}
+	switch base {
+	case HexBase, OctalBase, BinaryBase:
+		return formatUnsignedInBase(pattern, base)
+	case AllBases:
+		return fmt.Sprintf("%s (%s, %s, %s)", decimal, formatUnsignedInBase(pattern, HexBase),
+			formatUnsignedInBase(pattern, OctalBase), formatUnsignedInBase(pattern, BinaryBase))
+	default:
+		return decimal
+	}
+}
+
+// Flags returns the flags raised by computing x op y in the word. The result
+// is computed exactly, once treating the operands as unsigned numbers for the
+// carry flag and once as signed numbers for the overflow flag.
+func (w WordSize) Flags(op string, x, y int) WordFlags {
+	unsigned := WordSize{w.Bits, false}
+	signed := WordSize{w.Bits, true}
+	return WordFlags{
+		Carry:    !unsigned.fits(exactResult(op, unsigned.Wrap(x), unsigned.Wrap(y), false)),
+		Overflow: !signed.fits(exactResult(op, signed.Wrap(x), signed.Wrap(y), true)),
+	}
+}
+
+// fits reports whether x can be held by the word. A nil x has no result that
+// could overflow.
+func (w WordSize) fits(x *big.Int) bool {
+	if x == nil {
+		return true
+	}
+	min, max := new(big.Int), new(big.Int).SetUint64(w.mask())
+	if w.Signed {
+		max.Rsh(max, 1)
+		min.Neg(max).Sub(min, big.NewInt(1))
+	}
+	return x.Cmp(min) >= 0 && x.Cmp(max) <= 0
+}
+
+// exactResult computes x op y without any limit on its size. x and y are
+// read as unsigned 64-bit numbers unless signed is set. It returns nil for
+// operators that cannot carry or overflow.
+func exactResult(op string, x, y int, signed bool) *big.Int {
+	a, b := big.NewInt(int64(x)), big.NewInt(int64(y))
+	if !signed {
+		a.SetUint64(uint64(x))
+		b.SetUint64(uint64(y))
+	}
+	switch op {
+	case "+":
+		return a.Add(a, b)
+	case "-":
+		return a.Sub(a, b)
+	case "*":
+		return a.Mul(a, b)
+	case "/":
+		if b.Sign() == 0 {
+			return nil
+		}
+		return a.Quo(a, b)
+	case "^", "<<":
+		if b.Sign() < 0 {
+			return nil
+		}
+		if b.Cmp(big.NewInt(maxWordExponent)) > 0 {
+			b.SetInt64(maxWordExponent)
+		}
+		if op == "<<" {
+			return a.Lsh(a, uint(b.Int64()))
+		}
+		return a.Exp(a, b, nil)
+	}
+	return nil
+}
+
+// applyWordOperator computes x op y for two integers in the word size w,
+// records the flags raised and wraps the result.
+func applyWordOperator(w WordSize, op string, x, y int) (Value, error) {
+	x, y = w.Wrap(x), w.Wrap(y)
+	var v Value
+	var err error
+	switch {
+	case op == "rol":
+		v = IntValue(arithmetic.RotateLeftWidth(x, y, w.Bits))
+	case op == "ror":
+		v = IntValue(arithmetic.RotateRightWidth(x, y, w.Bits))
+	case !w.Signed && op == "/" && y != 0:
+		v = IntValue(int(w.Pattern(x) / w.Pattern(y)))
+	case !w.Signed && op == ">>" && y >= 0:
+		v = IntValue(int(w.Pattern(x) >> uint(y)))
+	case IsBitwiseOperator(op):
+		v, err = applyBitwiseOperator(op, x, y)
+	default:
+		v, err = applyWrappingOperator(op, x, y)
+	}
+	if err != nil || v.Kind != IntKind {
+		return v, err
+	}
+	CurrentSession.Flags.Record(w.Flags(op, x, y))
+	return IntValue(w.Wrap(v.Int)), nil
+}
+
+// wordNegate computes -x in the word size w. Only the flag matching the
+// signedness of the word is raised, so that typing -128 in an int8 or -1 in a
+// uint8 is not reported as an overflow and a carry respectively.
+func wordNegate(w WordSize, x int) Value {
+	exact := new(big.Int).Neg(big.NewInt(int64(x)))
+	if w.Signed {
+		CurrentSession.Flags.Record(WordFlags{Overflow: !w.fits(exact)})
+	} else {
+		CurrentSession.Flags.Record(WordFlags{Carry: !w.fits(exact)})
+	}
+	return IntValue(w.Wrap(-x))
+}
+
+// Record raises the flags set in f.
+func (f *WordFlags) Record(raised WordFlags) {
+	f.Carry = f.Carry || raised.Carry
+	f.Overflow = f.Overflow || raised.Overflow
+}
+
+// String lists the raised flags, e.g. carry overflow.
+func (f WordFlags) String() string {
+	flags := make([]string, 0, 2)
+	if f.Carry {
+		flags = append(flags, "carry")
+	}
+	if f.Overflow {
+		flags = append(flags, "overflow")
+	}
+	return strings.Join(flags, " ")
+}
+
+// ExecuteWordCommand shows the word size of the session, or changes it when
+// one is given.
+func ExecuteWordCommand(args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: word int8|int16|int32|int64|uint8|uint16|uint32|uint64|off")
+	}
+	if len(args) == 1 {
+		w, err := ParseWordSize(args[0])
+		if err != nil {
+			return err
+		}
+		CurrentSession.Word = w
+	}
+	fmt.Printf("Word size: %s.\n", CurrentSession.Word)
+	return nil
+}
+
+// PrintWordStatus prints the bit pattern of an integer result and the flags
+// raised while computing it when a word size is set.
+func PrintWordStatus(v Value) {
+	w := CurrentSession.Word
+	if !w.Enabled() || v.Kind != IntKind {
+		return
+	}
+	status := fmt.Sprintf("%-6s %s", w, w.BitPattern(v.Int))
+	if flags := CurrentSession.Flags.String(); flags != "" {
+		status += "  [" + flags + "]"
+	}
+	fmt.Println(status)
+}